@@ -1,18 +1,76 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rigbyel/ad-market/internal/config"
+	accountadverts "github.com/rigbyel/ad-market/internal/http-server/handlers/account/adverts"
+	accountexport "github.com/rigbyel/ad-market/internal/http-server/handlers/account/export"
+	accountpassword "github.com/rigbyel/ad-market/internal/http-server/handlers/account/password"
+	accountremove "github.com/rigbyel/ad-market/internal/http-server/handlers/account/remove"
+	accountshow "github.com/rigbyel/ad-market/internal/http-server/handlers/account/show"
+	accountupdate "github.com/rigbyel/ad-market/internal/http-server/handlers/account/update"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/account/verifyemail"
+	adminaudit "github.com/rigbyel/ad-market/internal/http-server/handlers/admin/audit"
+	adminban "github.com/rigbyel/ad-market/internal/http-server/handlers/admin/ban"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/admin/baninfo"
+	adminrole "github.com/rigbyel/ad-market/internal/http-server/handlers/admin/role"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/admin/unban"
 	adcreate "github.com/rigbyel/ad-market/internal/http-server/handlers/advert/create"
+	adhide "github.com/rigbyel/ad-market/internal/http-server/handlers/advert/hide"
+	adreport "github.com/rigbyel/ad-market/internal/http-server/handlers/advert/report"
+	apikeycreate "github.com/rigbyel/ad-market/internal/http-server/handlers/apikey/create"
+	apikeylist "github.com/rigbyel/ad-market/internal/http-server/handlers/apikey/list"
+	apikeyrevoke "github.com/rigbyel/ad-market/internal/http-server/handlers/apikey/revoke"
+	emailverify "github.com/rigbyel/ad-market/internal/http-server/handlers/email/verify"
 	"github.com/rigbyel/ad-market/internal/http-server/handlers/feed/show"
+	identitylink "github.com/rigbyel/ad-market/internal/http-server/handlers/identity/link"
+	identitylist "github.com/rigbyel/ad-market/internal/http-server/handlers/identity/list"
+	identityunlink "github.com/rigbyel/ad-market/internal/http-server/handlers/identity/unlink"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/keys/jwks"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/moderation/approve"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/moderation/banauthor"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/moderation/queue"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/moderation/reject"
+	oidccallback "github.com/rigbyel/ad-market/internal/http-server/handlers/oidc/callback"
+	oidcstart "github.com/rigbyel/ad-market/internal/http-server/handlers/oidc/start"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/password/forgot"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/password/reset"
+	sessionlist "github.com/rigbyel/ad-market/internal/http-server/handlers/session/list"
+	sessionrevoke "github.com/rigbyel/ad-market/internal/http-server/handlers/session/revoke"
+	sessionrevokeall "github.com/rigbyel/ad-market/internal/http-server/handlers/session/revokeall"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/token/refresh"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/twofactor/confirm"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/twofactor/disable"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/twofactor/enroll"
+	tfverify "github.com/rigbyel/ad-market/internal/http-server/handlers/twofactor/verify"
+	useradverts "github.com/rigbyel/ad-market/internal/http-server/handlers/user/adverts"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/user/appeal"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/user/available"
 	"github.com/rigbyel/ad-market/internal/http-server/handlers/user/login"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/user/logout"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/user/profile"
 	"github.com/rigbyel/ad-market/internal/http-server/handlers/user/register"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
 	"github.com/rigbyel/ad-market/internal/http-server/middleware/cors"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/problem"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/verified"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/credentials"
+	verifylink "github.com/rigbyel/ad-market/internal/lib/emailverify"
+	"github.com/rigbyel/ad-market/internal/lib/jwt"
+	"github.com/rigbyel/ad-market/internal/lib/mailer"
+	"github.com/rigbyel/ad-market/internal/lib/oidc"
+	"github.com/rigbyel/ad-market/internal/lib/passhash"
+	"github.com/rigbyel/ad-market/internal/lib/rbac"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
 	"github.com/rigbyel/ad-market/internal/storage"
 )
 
@@ -36,6 +94,13 @@ func main() {
 		log.Error("failed to init storage", slog.String("err", err.Error()))
 	}
 
+	// initializing token signing keys
+	tokens, err := setupTokens(cfg)
+	if err != nil {
+		log.Error("failed to load signing keys", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
 	// intializing chi router
 	router := chi.NewRouter()
 
@@ -45,12 +110,169 @@ func main() {
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
+	router.Use(problem.New(log))
+
+	// initializing password hashing
+	passwords, err := setupPasswords(cfg)
+	if err != nil {
+		log.Error("failed to init password hashing", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	// initializing rules for new passwords
+	policy, err := setupPasswordPolicy(cfg)
+	if err != nil {
+		log.Error("failed to init password policy", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	loginPolicy := &validate.LoginPolicy{AllowCyrillic: cfg.Login.AllowCyrillic}
+
+	authMiddleware := auth.New(log, tokens, cfg.Auth.LegacyHeader, storage)
+
+	// limiting failed login attempts, probing for taken logins and password reset requests
+	counter := setupCounter(cfg, storage)
+	if cfg.BruteForce.CleanupInterval <= 0 {
+		log.Error("brute_force.cleanup_interval must be positive")
+		os.Exit(1)
+	}
+	go pruneFailures(log, cfg, counter)
+	guard := setupGuard(cfg, counter)
+	takenLogins := setupLimiter(cfg, counter, "taken-login", cfg.BruteForce.TakenLoginThreshold)
+	resetRequests := setupLimiter(cfg, counter, "password-reset", cfg.Password.ResetRequestThreshold)
+
+	mail, err := setupMailer(cfg)
+	if err != nil {
+		log.Error("failed to init mailer", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+	verifier, err := setupVerifier(cfg, mail)
+	if err != nil {
+		log.Error("failed to init email verification", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+	requireVerified := verified.New(log, storage, cfg.Email.RequireVerified)
+
+	providers := setupProviders(cfg)
+
+	// handlers for anonymous users only
+	router.Group(func(r chi.Router) {
+		r.Use(authMiddleware.RequireAnonymous)
+
+		r.Post("/register", register.New(log, storage, passwords, policy, loginPolicy, takenLogins, verifier))
+		r.Get("/register/available", available.New(log, storage, loginPolicy, takenLogins))
+		r.Post("/login", login.New(log, storage, passwords, tokens, guard, cfg.TokenTL, cfg.Auth.RefreshTokenTL, cfg.TwoFactor.ChallengeTL))
+		r.Post("/login/2fa", tfverify.New(
+			log, storage, tokens, guard, cfg.TokenTL, cfg.Auth.RefreshTokenTL, cfg.TwoFactor.MaxAttempts,
+		))
+
+		// login with external provider, the user is redirected to it
+		r.Get("/oidc/{provider}/login", oidcstart.New(log, storage, providers, cfg.OIDC.StateTL))
+
+		// banned users can't authorize, so appeal is checked by credentials
+		r.Post("/appeal", appeal.New(log, storage, passwords, guard))
+	})
+
+	// public handlers, aware of the authorized user if there is one
+	router.Group(func(r chi.Router) {
+		r.Use(authMiddleware.OptionalScope(rbac.ScopeFeedRead))
+
+		r.Get("/feed", show.New(log, storage))
+		r.Get("/users/{login}", profile.New(log, storage))
+		r.Get("/users/{login}/adverts", useradverts.New(log, storage))
+	})
+
+	// handlers authorized by refresh token, so they work with expired access tokens
+	router.Post("/token/refresh", refresh.New(log, storage, tokens, cfg.TokenTL, cfg.Auth.RefreshTokenTL))
+	router.Post("/logout", logout.New(log, storage))
 
-	// handlers
-	router.Post("/register", register.New(log, storage, cfg.JwtSecret))
-	router.Post("/login", login.New(log, storage, cfg.JwtSecret, cfg.TokenTL))
-	router.Post("/advert", adcreate.New(log, storage, cfg.JwtSecret))
-	router.Get("/feed", show.New(log, storage, cfg.JwtSecret))
+	// forgotten password is reset with a token delivered to the user
+	router.Post("/password/forgot", forgot.New(log, storage, mail, resetRequests, cfg.Password.ResetTokenTL))
+	router.Post("/password/reset", reset.New(log, storage, passwords, policy, guard))
+
+	// the provider sends the user back here after login or linking identity to account
+	router.Get("/oidc/{provider}/callback", oidccallback.New(
+		log, storage, providers, loginPolicy, tokens, cfg.TokenTL, cfg.Auth.RefreshTokenTL, cfg.TwoFactor.ChallengeTL,
+	))
+
+	// email is confirmed by the link sent to it
+	router.Get("/email/verify", emailverify.New(log, storage, verifier))
+
+	// public keys for other services verifying our tokens
+	// served at /.well-known/jwks.json, URLFormat middleware strips the extension
+	router.Get("/.well-known/jwks", jwks.New(log, tokens))
+
+	// handlers also available to API keys with the matching scope
+	router.With(authMiddleware.RequireScope(rbac.ScopeAdvertsWrite), requireVerified).
+		Post("/advert", adcreate.New(log, storage))
+
+	// the authorized user's own account
+	router.Route("/me", func(r chi.Router) {
+		r.With(authMiddleware.RequireScope(rbac.ScopeAccountRead)).Get("/", accountshow.New(log, storage))
+		r.With(authMiddleware.RequireScope(rbac.ScopeAdvertsRead)).Get("/adverts", accountadverts.New(log, storage))
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.RequireAuth)
+
+			r.Patch("/", accountupdate.New(log, storage, verifier))
+			r.Delete("/", accountremove.New(log, storage, passwords, guard))
+			r.Get("/export", accountexport.New(log, storage))
+			r.Post("/email/verify", verifyemail.New(log, storage, verifier))
+			r.Post("/password", accountpassword.New(log, storage, passwords, policy, guard))
+
+			r.Post("/2fa", enroll.New(log, storage, cfg.TwoFactor.Issuer))
+			r.Post("/2fa/confirm", confirm.New(log, storage))
+			r.Delete("/2fa", disable.New(log, storage, passwords, guard))
+
+			r.Get("/sessions", sessionlist.New(log, storage))
+			r.Delete("/sessions", sessionrevokeall.New(log, storage))
+			r.Delete("/sessions/{id}", sessionrevoke.New(log, storage))
+
+			r.Get("/api-keys", apikeylist.New(log, storage))
+			r.Post("/api-keys", apikeycreate.New(log, storage))
+			r.Delete("/api-keys/{id}", apikeyrevoke.New(log, storage))
+
+			r.Get("/identities", identitylist.New(log, storage))
+			r.Post("/identities/{provider}", identitylink.New(log, storage, providers, cfg.OIDC.StateTL))
+			r.Delete("/identities/{provider}", identityunlink.New(log, storage))
+		})
+	})
+
+	// handlers for authorized users
+	router.Group(func(r chi.Router) {
+		r.Use(authMiddleware.RequireAuth)
+
+		r.With(authMiddleware.RequirePermission(rbac.PermHideAnyAdvert)).
+			Post("/advert/{id}/hide", adhide.New(log, storage))
+		r.Post("/advert/{id}/report", adreport.New(log, storage, cfg.Moderation.ReportThreshold))
+
+		// moderation of reported adverts
+		r.Route("/moderation", func(r chi.Router) {
+			r.Use(authMiddleware.RequirePermission(rbac.PermModerate))
+
+			r.Get("/queue", queue.New(log, storage))
+			r.Post("/adverts/{id}/approve", approve.New(log, storage))
+			r.Post("/adverts/{id}/reject", reject.New(log, storage))
+			r.Post("/adverts/{id}/ban-author", banauthor.New(log, storage))
+		})
+
+		// administration
+		r.Route("/admin", func(r chi.Router) {
+			r.With(authMiddleware.RequirePermission(rbac.PermManageRoles)).
+				Put("/users/{login}/role", adminrole.New(log, storage))
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePermission(rbac.PermBanUsers))
+
+				r.Get("/users/{login}/ban", baninfo.New(log, storage))
+				r.Post("/users/{login}/ban", adminban.New(log, storage))
+				r.Delete("/users/{login}/ban", unban.New(log, storage))
+			})
+
+			r.With(authMiddleware.RequirePermission(rbac.PermReadAudit)).
+				Get("/audit", adminaudit.New(log, storage))
+		})
+	})
 
 	// starting server
 	log.Info("starting server", slog.String("addres", cfg.Address))
@@ -70,6 +292,197 @@ func main() {
 
 }
 
+// setting up manager of token signing keys
+// shared jwt_secret is used unless asymmetric keys are configured
+func setupTokens(cfg *config.Config) (*jwt.Manager, error) {
+	opts := jwt.Options{
+		Issuer:   cfg.Auth.Issuer,
+		Audience: cfg.Auth.Audience,
+		Leeway:   cfg.Auth.Leeway,
+	}
+
+	if len(cfg.Auth.Keys) == 0 {
+		if cfg.JwtSecret == "" {
+			return nil, errors.New("jwt_secret is required unless auth.keys are set")
+		}
+
+		return jwt.NewHMACManager(opts, cfg.JwtSecret), nil
+	}
+
+	files := make([]jwt.KeyFile, 0, len(cfg.Auth.Keys))
+	for _, k := range cfg.Auth.Keys {
+		files = append(files, jwt.KeyFile{
+			ID:        k.ID,
+			Algorithm: k.Algorithm,
+			Path:      k.Path,
+		})
+	}
+
+	return jwt.LoadManager(opts, cfg.Auth.SigningKey, files)
+}
+
+// setting up hashing of passwords
+// new hashes are made by configured algorithm, the other one is kept for checking old hashes
+func setupPasswords(cfg *config.Config) (*credentials.Manager, error) {
+	bcrypt := passhash.NewBcrypt(cfg.Password.BcryptCost)
+	argon2id := passhash.NewArgon2id(cfg.Password.Argon2Memory, cfg.Password.Argon2Time, cfg.Password.Argon2Threads)
+
+	var hashes *passhash.Manager
+
+	switch cfg.Password.HashAlgorithm {
+	case "argon2id":
+		hashes = passhash.New(argon2id, bcrypt)
+	case "bcrypt":
+		hashes = passhash.New(bcrypt, argon2id)
+	default:
+		return nil, fmt.Errorf("%w: %s", passhash.ErrUnknownAlgorithm, cfg.Password.HashAlgorithm)
+	}
+
+	return credentials.New(hashes)
+}
+
+// setting up rules for new passwords
+func setupPasswordPolicy(cfg *config.Config) (*validate.PasswordPolicy, error) {
+	// longer passwords can't be hashed by bcrypt
+	const bcryptMaxLen = 72
+
+	if cfg.Password.HashAlgorithm == "bcrypt" && (cfg.Password.MaxLength <= 0 || cfg.Password.MaxLength > bcryptMaxLen) {
+		return nil, fmt.Errorf("password max_length should be from 1 to %d with bcrypt", bcryptMaxLen)
+	}
+
+	policy := &validate.PasswordPolicy{
+		MinLen:        cfg.Password.MinLength,
+		MaxLen:        cfg.Password.MaxLength,
+		RequireUpper:  cfg.Password.RequireUpper,
+		RequireLower:  cfg.Password.RequireLower,
+		RequireDigit:  cfg.Password.RequireDigit,
+		RequireSymbol: cfg.Password.RequireSymbol,
+		CheckLogin:    cfg.Password.CheckLogin,
+	}
+
+	if cfg.Password.BlocklistPath != "" {
+		if err := policy.LoadBlocklist(cfg.Password.BlocklistPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+// setting up counter of failed attempts
+// they are counted in sqlite unless memory storage is configured
+func setupCounter(cfg *config.Config, storage *storage.Storage) bruteforce.Counter {
+	if cfg.BruteForce.Storage == "memory" {
+		return bruteforce.NewMemoryCounter()
+	}
+
+	return storage
+}
+
+// periodically removes failures older than any policy counts
+// keys that are never checked again would keep them forever otherwise
+func pruneFailures(log *slog.Logger, cfg *config.Config, counter bruteforce.Counter) {
+	// all policies share window and lockout duration
+	retention := bruteforce.Policy{
+		Window:          cfg.BruteForce.Window,
+		LockoutDuration: cfg.BruteForce.LockoutDuration,
+	}.Retention()
+
+	ticker := time.NewTicker(cfg.BruteForce.CleanupInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if err := counter.PruneLoginFailures(now.Add(-retention)); err != nil {
+			log.Error("failed to prune failed attempts", slog.String("err", err.Error()))
+		}
+	}
+}
+
+// setting up guard against password guessing
+func setupGuard(cfg *config.Config, counter bruteforce.Counter) *bruteforce.Guard {
+	login := bruteforce.Policy{
+		Window:           cfg.BruteForce.Window,
+		DelayAfter:       cfg.BruteForce.LoginDelayAfter,
+		BaseDelay:        cfg.BruteForce.BaseDelay,
+		MaxDelay:         cfg.BruteForce.MaxDelay,
+		LockoutThreshold: cfg.BruteForce.LoginLockoutThreshold,
+		LockoutDuration:  cfg.BruteForce.LockoutDuration,
+	}
+
+	ip := login
+	ip.DelayAfter = cfg.BruteForce.IPDelayAfter
+	ip.LockoutThreshold = cfg.BruteForce.IPLockoutThreshold
+
+	return bruteforce.NewGuard(counter, login, ip)
+}
+
+// setting up limiter locking key after threshold is reached within brute_force window
+func setupLimiter(cfg *config.Config, counter bruteforce.Counter, name string, threshold int) *bruteforce.Limiter {
+	return bruteforce.NewLimiter(counter, name, bruteforce.Policy{
+		Window:           cfg.BruteForce.Window,
+		LockoutThreshold: threshold,
+		LockoutDuration:  cfg.BruteForce.LockoutDuration,
+	})
+}
+
+// setting up mailer delivering messages to users
+// outbox keeps links and tokens in plain files, so it's accepted only in local and dev environments
+func setupMailer(cfg *config.Config) (mailer.Mailer, error) {
+	switch cfg.Email.Mailer {
+	case "smtp":
+		if cfg.Email.SMTP.Host == "" {
+			return nil, errors.New("email.smtp.host is not set")
+		}
+
+		return mailer.NewSMTPMailer(cfg.Email.SMTP.Host, cfg.Email.SMTP.Port, cfg.Email.SMTP.Username, cfg.Email.SMTP.Password, cfg.Email.From), nil
+	case "outbox":
+		if cfg.Env != envLocal && cfg.Env != envDev {
+			return nil, fmt.Errorf("email.mailer outbox doesn't deliver messages and can't be used in %s environment", cfg.Env)
+		}
+
+		return mailer.NewOutboxMailer(cfg.Email.OutboxPath, cfg.Email.From), nil
+	default:
+		return nil, fmt.Errorf("unknown email.mailer: %s", cfg.Email.Mailer)
+	}
+}
+
+// setting up verifier of users' emails
+// links are signed with their own secret, so that key of access tokens isn't used for anything else
+func setupVerifier(cfg *config.Config, m mailer.Mailer) (*verifylink.Verifier, error) {
+	if cfg.Email.LinkSecret == "" {
+		return nil, errors.New("email.link_secret is required")
+	}
+	if cfg.Email.LinkSecret == cfg.JwtSecret {
+		return nil, errors.New("email.link_secret must differ from jwt_secret")
+	}
+
+	return verifylink.New([]byte(cfg.Email.LinkSecret), cfg.Email.LinkTL, cfg.Email.VerifyURL, m), nil
+}
+
+// setting up clients of OpenID Connect providers users can log in with
+func setupProviders(cfg *config.Config) oidc.Providers {
+	httpClient := &http.Client{Timeout: cfg.OIDC.Timeout}
+
+	providers := make(oidc.Providers, len(cfg.OIDC.Providers))
+	for _, p := range cfg.OIDC.Providers {
+		scopes := p.Scopes
+		if len(scopes) == 0 {
+			scopes = []string{"email", "profile"}
+		}
+
+		providers[p.Name] = oidc.New(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       scopes,
+		}, httpClient)
+	}
+
+	return providers
+}
+
 // setting up logger
 func setupLogger(env string) *slog.Logger {
 	var log *slog.Logger
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/rigbyel/ad-market/internal/lib/rbac"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+// function for granting role to an existing user, e.g. to create the first admin
+func main() {
+	var storagePath, login, role string
+
+	// reading parameters
+	flag.StringVar(&storagePath, "storage-path", "", "path to storage")
+	flag.StringVar(&login, "login", "", "login of the user")
+	flag.StringVar(&role, "role", string(models.RoleAdmin), "role to grant")
+	flag.Parse()
+
+	if storagePath == "" {
+		panic("storage-path is required")
+	}
+
+	if login == "" {
+		panic("login is required")
+	}
+
+	if !rbac.ValidRole(models.Role(role)) {
+		panic("unknown role: " + role)
+	}
+
+	s, err := storage.New(storagePath)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Stop()
+
+	if err := s.SetUserRole(login, models.Role(role)); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			fmt.Println("user not found, register it first:", login)
+
+			return
+		}
+
+		panic(err)
+	}
+
+	fmt.Printf("role %s granted to %s\n", role, login)
+}
@@ -2,7 +2,11 @@ module github.com/rigbyel/ad-market
 
 go 1.22.0
 
-require github.com/golang-migrate/migrate/v4 v4.17.0
+require (
+	github.com/golang-migrate/migrate/v4 v4.17.0
+	golang.org/x/crypto v0.19.0
+	golang.org/x/text v0.14.0
+)
 
 require (
 	github.com/ajg/form v1.5.1 // indirect
@@ -10,10 +14,8 @@ require (
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
-	golang.org/x/crypto v0.19.0 // indirect
 	golang.org/x/net v0.21.0 // indirect
 	golang.org/x/sys v0.17.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 )
 
 require (
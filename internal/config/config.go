@@ -16,14 +16,172 @@ type Config struct {
 	Env         string `yaml:"env" env-required:"true"`
 	StoragePath string `yaml:"storage_path" env-required:"true"`
 	HTTPServer  `yaml:"http_server" env-required:"true"`
-	JwtSecret   string `yaml:"jwt_secret" env-requires:"true"`
+	JwtSecret   string `yaml:"jwt_secret"` // required unless auth.keys are set, checked on startup
+	Auth        `yaml:"auth"`
+	Moderation  `yaml:"moderation"`
+	BruteForce  `yaml:"brute_force"`
+	Password    `yaml:"password"`
+	Email       `yaml:"email"`
+	TwoFactor   `yaml:"two_factor"`
+	Login       `yaml:"login"`
+	OIDC        `yaml:"oidc"`
 }
 
 type HTTPServer struct {
 	Address      string        `yaml:"address" env-required:"true"`
 	ReadTimeout  time.Duration `yaml:"read_timeout" env-default:"3s"`
 	WriteTimeout time.Duration `yaml:"write_timeout" env-default:"3s"`
-	TokenTL      time.Duration `yaml:"token_tl" env-default:"1h"`
+	TokenTL      time.Duration `yaml:"token_tl" env-default:"15m"`
+}
+
+type Auth struct {
+	// accept tokens from non-standard Authorization-access header for existing clients
+	LegacyHeader   bool          `yaml:"legacy_header" env-default:"true"`
+	RefreshTokenTL time.Duration `yaml:"refresh_token_tl" env-default:"720h"`
+
+	// registered claims of issued tokens
+	Issuer   string        `yaml:"issuer" env-default:"ad-market"`
+	Audience string        `yaml:"audience" env-default:"ad-market"`
+	Leeway   time.Duration `yaml:"leeway" env-default:"30s"`
+
+	// asymmetric keys for signing tokens, jwt_secret is used if there are none
+	SigningKey string   `yaml:"signing_key"`
+	Keys       []JWTKey `yaml:"keys"`
+}
+
+type Moderation struct {
+	// number of reports after which advert is hidden until moderator reviews it
+	ReportThreshold int `yaml:"report_threshold" env-default:"3"`
+}
+
+type BruteForce struct {
+	// where failed login attempts are counted: "sqlite" or "memory"
+	Storage string `yaml:"storage" env-default:"sqlite"`
+
+	// failed attempts older than window are not counted
+	Window time.Duration `yaml:"window" env-default:"15m"`
+
+	// delay before next attempt after too many failures, doubled with each failure
+	BaseDelay time.Duration `yaml:"base_delay" env-default:"1s"`
+	MaxDelay  time.Duration `yaml:"max_delay" env-default:"1m"`
+
+	LockoutDuration time.Duration `yaml:"lockout_duration" env-default:"15m"`
+
+	// how often failures that can't be counted anymore are removed
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"10m"`
+
+	// limits of failures for one account
+	LoginDelayAfter       int `yaml:"login_delay_after" env-default:"3"`
+	LoginLockoutThreshold int `yaml:"login_lockout_threshold" env-default:"10"`
+
+	// limits of failures from one ip address
+	IPDelayAfter       int `yaml:"ip_delay_after" env-default:"20"`
+	IPLockoutThreshold int `yaml:"ip_lockout_threshold" env-default:"100"`
+
+	// number of taken logins one ip address may hit when registering or checking availability
+	// within window before it's locked, so that existing accounts can't be listed by bulk probing
+	TakenLoginThreshold int `yaml:"taken_login_threshold" env-default:"5"`
+}
+
+type Password struct {
+	// lifetime of password reset token
+	ResetTokenTL time.Duration `yaml:"reset_token_tl" env-default:"1h"`
+
+	// number of reset requests for one account within brute_force window
+	ResetRequestThreshold int `yaml:"reset_request_threshold" env-default:"3"`
+
+	// rules for new passwords, max_length is in bytes and can't exceed 72 with bcrypt
+	MinLength     int  `yaml:"min_length" env-default:"8"`
+	MaxLength     int  `yaml:"max_length" env-default:"72"`
+	RequireUpper  bool `yaml:"require_upper" env-default:"true"`
+	RequireLower  bool `yaml:"require_lower" env-default:"true"`
+	RequireDigit  bool `yaml:"require_digit" env-default:"true"`
+	RequireSymbol bool `yaml:"require_symbol" env-default:"false"`
+	CheckLogin    bool `yaml:"check_login" env-default:"true"`
+
+	// file with common and breached passwords that can't be used, one per line
+	BlocklistPath string `yaml:"blocklist_path"`
+
+	// algorithm of new password hashes: "argon2id" or "bcrypt"
+	// hashes made by the other one are still accepted and replaced on login
+	HashAlgorithm string `yaml:"hash_algorithm" env-default:"argon2id"`
+	BcryptCost    int    `yaml:"bcrypt_cost" env-default:"10"`
+
+	// memory in KiB, number of passes and parallelism of argon2id
+	Argon2Memory  uint32 `yaml:"argon2_memory" env-default:"19456"`
+	Argon2Time    uint32 `yaml:"argon2_time" env-default:"2"`
+	Argon2Threads uint8  `yaml:"argon2_threads" env-default:"1"`
+}
+
+type Email struct {
+	// users without verified email can't post adverts
+	RequireVerified bool `yaml:"require_verified" env-default:"false"`
+
+	// address of verification endpoint, token is added as query parameter
+	VerifyURL string `yaml:"verify_url" env-default:"http://localhost:8082/email/verify"`
+
+	// secret for signing verification links, it must differ from jwt_secret
+	LinkSecret string        `yaml:"link_secret" env-required:"true"`
+	LinkTL     time.Duration `yaml:"link_tl" env-default:"24h"`
+
+	// "outbox" writes messages as .eml files to OutboxPath, "smtp" sends them via SMTP server
+	Mailer     string `yaml:"mailer" env-default:"outbox"`
+	OutboxPath string `yaml:"outbox_path" env-default:"./storage/outbox"`
+	From       string `yaml:"from" env-default:"ad-market <noreply@localhost>"`
+	SMTP       SMTP   `yaml:"smtp"`
+}
+
+type Login struct {
+	// allow logins in cyrillic letters, mixing them with latin ones is still forbidden
+	AllowCyrillic bool `yaml:"allow_cyrillic" env-default:"false"`
+}
+
+type TwoFactor struct {
+	// issuer shown in authenticator app
+	Issuer string `yaml:"issuer" env-default:"ad-market"`
+
+	// lifetime of token issued on login to enter the code with
+	ChallengeTL time.Duration `yaml:"challenge_tl" env-default:"5m"`
+
+	// number of wrong codes after which login has to be started again
+	MaxAttempts int `yaml:"max_attempts" env-default:"5"`
+}
+
+type OIDC struct {
+	// lifetime of authorization request, the user has to return from provider within it
+	StateTL time.Duration `yaml:"state_tl" env-default:"10m"`
+
+	// timeout of requests to providers
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+
+	Providers []OIDCProvider `yaml:"providers"`
+}
+
+type OIDCProvider struct {
+	// name used in urls: /oidc/{name}/login
+	Name         string `yaml:"name" env-required:"true"`
+	Issuer       string `yaml:"issuer" env-required:"true"`
+	ClientID     string `yaml:"client_id" env-required:"true"`
+	ClientSecret string `yaml:"client_secret"`
+
+	// address of /oidc/{name}/callback registered with the provider
+	RedirectURL string `yaml:"redirect_url" env-required:"true"`
+
+	// scopes requested in addition to openid, "email profile" if empty
+	Scopes []string `yaml:"scopes"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type JWTKey struct {
+	ID        string `yaml:"id" env-required:"true"`
+	Algorithm string `yaml:"algorithm" env-required:"true"` // RS256, RS384, RS512 or EdDSA
+	Path      string `yaml:"path" env-required:"true"`      // PEM file with private or, for retired keys, public key
 }
 
 // loading config from configPath
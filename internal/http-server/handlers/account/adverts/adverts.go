@@ -0,0 +1,95 @@
+package adverts
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/feed"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type Advert struct {
+	Id       int64     `json:"id"`
+	Header   string    `json:"header"`
+	Body     string    `json:"body"`
+	ImageURL string    `json:"image_url,omitempty"`
+	Price    int       `json:"price"`
+	Date     time.Time `json:"date"`
+	Status   string    `json:"status"`
+
+	// reason given by moderator when advert was rejected
+	ModerationReason string `json:"moderation_reason,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	Adverts *[]Advert `json:"adverts"`
+}
+
+type AdProvider interface {
+	UserAdverts(login string) (*[]models.Advert, error)
+}
+
+// New creates a new HandlerFunc for showing all adverts of the authorized user
+func New(log *slog.Logger, adProv AdProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.account.adverts.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		login, _ := auth.UserLogin(r.Context())
+
+		// get user's adverts in any status from storage
+		adverts, err := adProv.UserAdverts(login)
+		if err != nil {
+			log.Error("failed to get adverts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// sorting and paginating adverts the same way as in feed
+		page, err := feed.Page(r, adverts)
+		if err != nil {
+			log.Info("failed to get page of adverts", slog.String("error", err.Error()))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.ErrorOf(err))
+
+			return
+		}
+
+		var pageAdverts []Advert
+		for _, ad := range page {
+			pageAdverts = append(pageAdverts, Advert{
+				Id:       ad.Id,
+				Header:   ad.Header,
+				Body:     ad.Body,
+				ImageURL: ad.ImageURL,
+				Price:    ad.Price,
+				Date:     ad.Date,
+				Status:   ad.Status,
+
+				ModerationReason: ad.ModerationReason,
+			})
+		}
+
+		log.Info("account adverts accessed")
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Adverts:  &pageAdverts,
+		})
+	}
+}
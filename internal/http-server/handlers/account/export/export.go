@@ -0,0 +1,337 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type Account struct {
+	Id               int64      `json:"id"`
+	Login            string     `json:"login"`
+	Email            string     `json:"email,omitempty"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at,omitempty"`
+	Bio              string     `json:"bio"`
+	AvatarURL        string     `json:"avatar_url"`
+	Rating           float64    `json:"rating"`
+	Role             string     `json:"role"`
+	RegDate          time.Time  `json:"reg_date"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+}
+
+type Advert struct {
+	Id               int64     `json:"id"`
+	Header           string    `json:"header"`
+	Body             string    `json:"body"`
+	ImageURL         string    `json:"image_url"`
+	Price            int       `json:"price"`
+	Date             time.Time `json:"date"`
+	Status           string    `json:"status"`
+	ModerationReason string    `json:"moderation_reason,omitempty"`
+}
+
+type Report struct {
+	AdvertId   int64      `json:"advert_id"`
+	Reason     string     `json:"reason"`
+	Comment    string     `json:"comment,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+type Ban struct {
+	Reason     string     `json:"reason"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LiftedAt   *time.Time `json:"lifted_at,omitempty"`
+	Appeal     string     `json:"appeal,omitempty"`
+	AppealedAt *time.Time `json:"appealed_at,omitempty"`
+}
+
+type Session struct {
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type APIKey struct {
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type Identity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditEntry struct {
+	Event      string    `json:"event"`
+	ActorLogin string    `json:"actor_login,omitempty"`
+	Target     string    `json:"target,omitempty"`
+	Details    string    `json:"details,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	RequestId  string    `json:"request_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type UserDataProvider interface {
+	UserData(userID int64) (*models.UserData, error)
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for exporting all data stored about the authorized user
+// data is returned as ZIP archive with a JSON file for each kind of data
+func New(log *slog.Logger, dataProvider UserDataProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.account.export.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		data, err := dataProvider.UserData(claims.ID)
+		if err != nil {
+			log.Error("failed to get user data", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// archive is built in memory, so that failure can still be reported with error response
+		archive, err := build(data)
+		if err != nil {
+			log.Error("failed to build archive", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("user data exported")
+
+		audit.Record(log, dataProvider, r, models.AuditEntry{
+			Event:      models.AuditUserExport,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     "user:" + claims.Login,
+		})
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "ad-market-"+data.User.Login+".zip"))
+		w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+		w.Header().Set("Cache-Control", "no-store")
+
+		if _, err := w.Write(archive); err != nil {
+			log.Error("failed to send archive", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// builds ZIP archive with user data
+func build(data *models.UserData) ([]byte, error) {
+	u := data.User
+
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"account.json", Account{
+			Id:               u.Id,
+			Login:            u.Login,
+			Email:            u.Email,
+			EmailVerifiedAt:  optional(u.EmailVerifiedAt),
+			Bio:              u.Bio,
+			AvatarURL:        u.AvatarURL,
+			Rating:           u.Rating,
+			Role:             string(u.Role),
+			RegDate:          u.RegDate,
+			TwoFactorEnabled: data.TwoFactorEnabled,
+		}},
+		{"adverts.json", adverts(data.Adverts)},
+		{"reports.json", reports(data.Reports)},
+		{"bans.json", bans(data.Bans)},
+		{"sessions.json", sessions(data.Sessions)},
+		{"api_keys.json", apiKeys(data.APIKeys)},
+		{"identities.json", identities(data.Identities)},
+		{"audit_log.json", auditLog(data.AuditLog, u.Id)},
+	}
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(f.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func adverts(list []models.Advert) []Advert {
+	res := []Advert{}
+	for _, ad := range list {
+		res = append(res, Advert{
+			Id:               ad.Id,
+			Header:           ad.Header,
+			Body:             ad.Body,
+			ImageURL:         ad.ImageURL,
+			Price:            ad.Price,
+			Date:             ad.Date,
+			Status:           ad.Status,
+			ModerationReason: ad.ModerationReason,
+		})
+	}
+
+	return res
+}
+
+func reports(list []models.Report) []Report {
+	res := []Report{}
+	for _, rep := range list {
+		res = append(res, Report{
+			AdvertId:   rep.AdvertId,
+			Reason:     string(rep.Reason),
+			Comment:    rep.Comment,
+			CreatedAt:  rep.CreatedAt,
+			ResolvedAt: optional(rep.ResolvedAt),
+		})
+	}
+
+	return res
+}
+
+func bans(list []models.Ban) []Ban {
+	res := []Ban{}
+	for _, ban := range list {
+		res = append(res, Ban{
+			Reason:     ban.Reason,
+			CreatedAt:  ban.CreatedAt,
+			ExpiresAt:  optional(ban.ExpiresAt),
+			LiftedAt:   optional(ban.LiftedAt),
+			Appeal:     ban.Appeal,
+			AppealedAt: optional(ban.AppealedAt),
+		})
+	}
+
+	return res
+}
+
+func sessions(list []models.Session) []Session {
+	res := []Session{}
+	for _, s := range list {
+		res = append(res, Session{
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			RevokedAt:  optional(s.RevokedAt),
+		})
+	}
+
+	return res
+}
+
+// hashes of keys are not exported
+func apiKeys(list []models.APIKey) []APIKey {
+	res := []APIKey{}
+	for _, k := range list {
+		res = append(res, APIKey{
+			Name:       k.Name,
+			Prefix:     k.Prefix,
+			Scopes:     k.Scopes,
+			CreatedAt:  k.CreatedAt,
+			ExpiresAt:  optional(k.ExpiresAt),
+			LastUsedAt: optional(k.LastUsedAt),
+			RevokedAt:  optional(k.RevokedAt),
+		})
+	}
+
+	return res
+}
+
+func identities(list []models.Identity) []Identity {
+	res := []Identity{}
+	for _, i := range list {
+		res = append(res, Identity{
+			Provider:  i.Provider,
+			Subject:   i.Subject,
+			Email:     i.Email,
+			CreatedAt: i.CreatedAt,
+		})
+	}
+
+	return res
+}
+
+// only the user's own actions are exported in full
+// identity, address and details of other actors are personal data of theirs
+func auditLog(list []models.AuditEntry, userID int64) []AuditEntry {
+	res := []AuditEntry{}
+	for _, e := range list {
+		entry := AuditEntry{
+			Event:     string(e.Event),
+			Target:    e.Target,
+			CreatedAt: e.CreatedAt,
+		}
+		if e.ActorId == userID {
+			entry.ActorLogin = e.ActorLogin
+			entry.Details = e.Details
+			entry.IP = e.IP
+			entry.RequestId = e.RequestId
+		}
+
+		res = append(res, entry)
+	}
+
+	return res
+}
+
+// zero time means the value is not set
+func optional(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
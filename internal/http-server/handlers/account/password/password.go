@@ -0,0 +1,152 @@
+package password
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/credentials"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type PasswordChanger interface {
+	UserByID(id int64) (*models.User, error)
+	SetPassword(userID int64, passHash []byte) error
+	RevokeOtherSessions(userID int64, keepID string) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for changing password of the authorized user
+// the current password is required, wrong guesses are limited by guard as on login
+// sessions on other devices are revoked after the change
+func New(
+	log *slog.Logger,
+	passwordChanger PasswordChanger,
+	passwords *credentials.Manager,
+	policy *validate.PasswordPolicy,
+	guard *bruteforce.Guard,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.account.password.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+		sessionID, _ := auth.SessionID(r.Context())
+
+		var req request.PasswordRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		ip := request.ClientIP(r)
+
+		wait, err := guard.Check(claims.Login, ip, time.Now())
+		if err != nil {
+			log.Error("failed to check password attempts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if wait > 0 {
+			log.Info("too many failed attempts", slog.String("user", claims.Login), slog.Duration("wait", wait))
+
+			w.Header().Set("Retry-After", bruteforce.RetryAfter(wait))
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, response.Error("too many failed attempts, try again later"))
+
+			return
+		}
+
+		user, err := passwordChanger.UserByID(claims.ID)
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// checking current password
+		if ok, _ := passwords.Verify(user, req.CurrentPassword); !ok {
+			log.Info("invalid current password")
+
+			if err := guard.Fail(user.Login, ip, time.Now()); err != nil {
+				log.Error("failed to record failed attempt", slog.String("error", err.Error()))
+			}
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("invalid current password"))
+
+			return
+		}
+
+		if validationErrs := policy.Validate(req.NewPassword, user.Login).OnField("new_password"); len(validationErrs) != 0 {
+			log.Info("invalid new password")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Invalid(validationErrs))
+
+			return
+		}
+
+		// hashing password
+		passHash, err := passwords.Hash(req.NewPassword)
+		if err != nil {
+			log.Error("failed to generate password hash", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		if err := passwordChanger.SetPassword(user.Id, passHash); err != nil {
+			log.Error("failed to change password", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// logging out other devices, someone there may know the old password
+		if err := passwordChanger.RevokeOtherSessions(user.Id, sessionID); err != nil {
+			log.Error("failed to revoke other sessions", slog.String("error", err.Error()))
+		}
+
+		log.Info("password changed", slog.String("user", user.Login))
+
+		audit.Record(log, passwordChanger, r, models.AuditEntry{
+			Event:      models.AuditPasswordChange,
+			ActorId:    user.Id,
+			ActorLogin: user.Login,
+			Target:     "user:" + user.Login,
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,130 @@
+package remove
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/credentials"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type UserDeleter interface {
+	UserByID(id int64) (*models.User, error)
+	DeleteUser(userID int64, entry *models.AuditEntry) error
+}
+
+// New creates a new HandlerFunc for deleting account of the authorized user
+// with their adverts, sessions and other data, the password is required
+// wrong guesses are limited by guard as on login
+func New(
+	log *slog.Logger,
+	userDeleter UserDeleter,
+	passwords *credentials.Manager,
+	guard *bruteforce.Guard,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.account.remove.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		var req request.AccountDeleteRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		ip := request.ClientIP(r)
+
+		wait, err := guard.Check(claims.Login, ip, time.Now())
+		if err != nil {
+			log.Error("failed to check password attempts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if wait > 0 {
+			log.Info("too many failed attempts", slog.String("user", claims.Login), slog.Duration("wait", wait))
+
+			w.Header().Set("Retry-After", bruteforce.RetryAfter(wait))
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, response.Error("too many failed attempts, try again later"))
+
+			return
+		}
+
+		user, err := userDeleter.UserByID(claims.ID)
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// users registered with external provider have to set a password first
+		if len(user.PassHash) == 0 {
+			log.Info("user has no password")
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("set a password before deleting the account"))
+
+			return
+		}
+
+		// stolen access token alone is not enough to delete the account
+		if ok, _ := passwords.Verify(user, req.Password); !ok {
+			log.Info("invalid password")
+
+			if err := guard.Fail(user.Login, ip, time.Now()); err != nil {
+				log.Error("failed to record failed attempt", slog.String("error", err.Error()))
+			}
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("invalid password"))
+
+			return
+		}
+
+		// deletion is recorded in the same transaction, without the user's personal data
+		err = userDeleter.DeleteUser(user.Id, &models.AuditEntry{
+			Event:     models.AuditUserDelete,
+			RequestId: middleware.GetReqID(r.Context()),
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Error("failed to delete user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("user deleted", slog.Int64("id", user.Id))
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,95 @@
+package show
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type Response struct {
+	response.Response
+	Id            int64      `json:"id"`
+	Login         string     `json:"login"`
+	Role          string     `json:"role"`
+	RegDate       *time.Time `json:"reg_date,omitempty"`
+	Rating        float64    `json:"rating"`
+	ActiveAdverts int        `json:"active_adverts"`
+	Bio           string     `json:"bio"`
+	AvatarURL     string     `json:"avatar_url"`
+	Email         string     `json:"email,omitempty"`
+	EmailVerified bool       `json:"email_verified"`
+}
+
+type AccountProvider interface {
+	User(login string) (*models.User, error)
+	ActiveAdvertsCount(login string) (int, error)
+}
+
+// New creates a new HandlerFunc for showing profile of the authorized user
+func New(log *slog.Logger, accProv AccountProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.account.show.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		login, _ := auth.UserLogin(r.Context())
+
+		// getting user info from storage
+		user, err := accProv.User(login)
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// counting user's active adverts
+		activeAdverts, err := accProv.ActiveAdvertsCount(user.Login)
+		if err != nil {
+			log.Error("failed to count adverts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("account accessed", slog.Int64("id", user.Id))
+
+		render.JSON(w, r, NewResponse(user, activeAdverts))
+	}
+}
+
+// NewResponse builds account response from user data
+func NewResponse(user *models.User, activeAdverts int) Response {
+	resp := Response{
+		Response:      response.OK(),
+		Id:            user.Id,
+		Login:         user.Login,
+		Role:          string(user.Role),
+		Rating:        user.Rating,
+		ActiveAdverts: activeAdverts,
+		Bio:           user.Bio,
+		Email:         user.Email,
+		EmailVerified: user.Email != "" && !user.EmailVerifiedAt.IsZero(),
+		AvatarURL:     user.AvatarURL,
+	}
+
+	if !user.RegDate.IsZero() {
+		resp.RegDate = &user.RegDate
+	}
+
+	return resp
+}
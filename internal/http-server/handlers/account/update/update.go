@@ -0,0 +1,154 @@
+package update
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	accountshow "github.com/rigbyel/ad-market/internal/http-server/handlers/account/show"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/emailverify"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type AccountUpdater interface {
+	User(login string) (*models.User, error)
+	ActiveAdvertsCount(login string) (int, error)
+	UpdateProfile(u *models.User) error
+	SetEmail(userID int64, email string) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for updating profile of the authorized user
+// verification link is sent by verifier when email is changed
+func New(log *slog.Logger, accUpdater AccountUpdater, verifier *emailverify.Verifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.account.update.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		login, _ := auth.UserLogin(r.Context())
+
+		var req request.ProfileRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		log.Info("request body decoded",
+			slog.Bool("bio", req.Bio != nil),
+			slog.Bool("avatar_url", req.AvatarURL != nil),
+			slog.Bool("email", req.Email != nil),
+		)
+
+		if req.Bio != nil {
+			*req.Bio = validate.NormalizeText(*req.Bio)
+		}
+		if req.Email != nil {
+			*req.Email = validate.NormalizeEmail(*req.Email)
+		}
+
+		// validating profile fields
+		validationErrs := validate.ValidateProfile(req)
+		if len(validationErrs) != 0 {
+			log.Error("invalid request")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Invalid(validationErrs))
+
+			return
+		}
+
+		// getting user info from storage
+		user, err := accUpdater.User(login)
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// only fields present in request are changed
+		if req.Bio != nil {
+			user.Bio = *req.Bio
+		}
+
+		if req.AvatarURL != nil {
+			user.AvatarURL = *req.AvatarURL
+		}
+
+		// changed email has to be verified again
+		emailChanged := req.Email != nil && *req.Email != user.Email
+		if emailChanged {
+			// the email isn't checked against other users, it may be taken only by verifying it
+			err := accUpdater.SetEmail(user.Id, *req.Email)
+			if err != nil {
+				log.Error("error changing email", slog.String("error", err.Error()))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, response.Error("internal error"))
+
+				return
+			}
+
+			user.Email = *req.Email
+			user.EmailVerifiedAt = time.Time{}
+
+			audit.Record(log, accUpdater, r, models.AuditEntry{
+				Event:      models.AuditEmailChange,
+				ActorId:    user.Id,
+				ActorLogin: user.Login,
+				Target:     "user:" + user.Login,
+			})
+		}
+
+		err = accUpdater.UpdateProfile(user)
+		if err != nil {
+			log.Error("error updating profile", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("error updating profile"))
+
+			return
+		}
+
+		activeAdverts, err := accUpdater.ActiveAdvertsCount(user.Login)
+		if err != nil {
+			log.Error("failed to count adverts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("profile updated", slog.Int64("id", user.Id))
+
+		if emailChanged && user.Email != "" {
+			if err := verifier.Send(user.Id, user.Email); err != nil {
+				log.Error("failed to send verification link", slog.String("error", err.Error()))
+			}
+		}
+
+		render.JSON(w, r, accountshow.NewResponse(user, activeAdverts))
+	}
+}
@@ -0,0 +1,73 @@
+package verifyemail
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/emailverify"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type UserProvider interface {
+	UserByID(id int64) (*models.User, error)
+}
+
+// New creates a new HandlerFunc for sending verification link to the authorized user's email again
+func New(log *slog.Logger, userProvider UserProvider, verifier *emailverify.Verifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.account.verifyemail.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		user, err := userProvider.UserByID(claims.ID)
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		if user.Email == "" {
+			log.Info("user has no email")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("email is not set"))
+
+			return
+		}
+
+		if !user.EmailVerifiedAt.IsZero() {
+			log.Info("email already verified")
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("email already verified"))
+
+			return
+		}
+
+		if err := verifier.Send(user.Id, user.Email); err != nil {
+			log.Error("failed to send verification link", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("failed to send verification link"))
+
+			return
+		}
+
+		log.Info("verification link sent", slog.Int64("user", user.Id))
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+)
+
+type Entry struct {
+	Id         int64     `json:"id"`
+	Event      string    `json:"event"`
+	ActorId    int64     `json:"actor_id,omitempty"`
+	ActorLogin string    `json:"actor,omitempty"`
+	Target     string    `json:"target,omitempty"`
+	Details    string    `json:"details,omitempty"`
+	IP         string    `json:"ip"`
+	RequestId  string    `json:"request_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Response struct {
+	response.Response
+	Entries []Entry `json:"entries"`
+}
+
+type AuditProvider interface {
+	AuditLog(filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// New creates a new HandlerFunc for querying audit log by admin
+// entries are filtered by query parameters and returned newest first
+func New(log *slog.Logger, auditProvider AuditProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.audit.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			log.Info("invalid filter", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+
+			return
+		}
+
+		entries, err := auditProvider.AuditLog(filter)
+		if err != nil {
+			log.Error("failed to get audit log", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		resp := Response{
+			Response: response.OK(),
+			Entries:  []Entry{},
+		}
+
+		for _, e := range entries {
+			resp.Entries = append(resp.Entries, Entry{
+				Id:         e.Id,
+				Event:      string(e.Event),
+				ActorId:    e.ActorId,
+				ActorLogin: e.ActorLogin,
+				Target:     e.Target,
+				Details:    e.Details,
+				IP:         e.IP,
+				RequestId:  e.RequestId,
+				CreatedAt:  e.CreatedAt,
+			})
+		}
+
+		log.Info("audit log accessed", slog.Int("entries", len(resp.Entries)))
+
+		render.JSON(w, r, resp)
+	}
+}
+
+// builds audit filter from query parameters
+// from and to are RFC 3339 timestamps, page starts with 1
+func parseFilter(query url.Values) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		Event:      models.AuditEvent(query.Get("event")),
+		ActorLogin: query.Get("actor"),
+		Target:     query.Get("target"),
+		IP:         query.Get("ip"),
+		RequestId:  query.Get("request_id"),
+		Limit:      constraints.AuditEntriesOnPage,
+	}
+
+	var err error
+
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, fmt.Errorf("from should be RFC 3339 timestamp")
+		}
+	}
+
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, fmt.Errorf("to should be RFC 3339 timestamp")
+		}
+	}
+
+	if pageStr := query.Get("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil || page <= 0 {
+			return filter, fmt.Errorf("page should be positive number")
+		}
+
+		filter.Offset = (page - 1) * constraints.AuditEntriesOnPage
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,174 @@
+package ban
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/rbac"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type Response struct {
+	response.Response
+	Id int64 `json:"id"`
+
+	// omitted for permanent bans
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type UserBanner interface {
+	User(login string) (*models.User, error)
+	SaveBan(ban *models.Ban) (*models.Ban, error)
+	RevokeUserSessions(userID int64) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for suspending a user for a duration or banning permanently
+func New(log *slog.Logger, userBanner UserBanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.ban.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+		login := chi.URLParam(r, "login")
+
+		if claims.Login == login {
+			log.Info("attempt to ban self")
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("can't ban yourself"))
+
+			return
+		}
+
+		var req request.BanRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		req.Reason = validate.NormalizeText(req.Reason)
+
+		if req.Reason == "" || validate.TextLength(req.Reason) > constraints.ModerationReasonMaxLen {
+			log.Info("invalid ban reason")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("reason is required and should be shorter than 500 characters"))
+
+			return
+		}
+
+		now := time.Now()
+		ban := &models.Ban{
+			Reason:    req.Reason,
+			CreatedAt: now,
+			CreatedBy: claims.ID,
+		}
+
+		// empty duration means permanent ban
+		if req.Duration != "" {
+			duration, err := time.ParseDuration(req.Duration)
+			if err != nil || duration <= 0 {
+				log.Info("invalid ban duration", slog.String("duration", req.Duration))
+
+				render.Status(r, http.StatusUnprocessableEntity)
+				render.JSON(w, r, response.Error("duration should be positive, e.g. 72h"))
+
+				return
+			}
+
+			ban.ExpiresAt = now.Add(duration)
+		}
+
+		user, err := userBanner.User(login)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("user", login))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("user not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// admins can't ban each other
+		if !rbac.Outranks(claims.Role, user.Role) {
+			log.Info("attempt to ban user of same or higher role", slog.String("user", login))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("can't ban user with the same or higher role"))
+
+			return
+		}
+
+		ban.UserId = user.Id
+
+		ban, err = userBanner.SaveBan(ban)
+		if err != nil {
+			log.Error("failed to ban user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// logging banned user out everywhere
+		if err := userBanner.RevokeUserSessions(user.Id); err != nil {
+			log.Error("failed to revoke sessions of banned user", slog.String("error", err.Error()))
+		}
+
+		log.Info("user banned", slog.String("user", login), slog.String("duration", req.Duration))
+
+		audit.Record(log, userBanner, r, models.AuditEntry{
+			Event:      models.AuditUserBan,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     "user:" + login,
+			Details:    fmt.Sprintf("duration=%s reason=%s", req.Duration, req.Reason),
+		})
+
+		resp := Response{
+			Response: response.OK(),
+			Id:       ban.Id,
+		}
+
+		if !ban.ExpiresAt.IsZero() {
+			resp.ExpiresAt = &ban.ExpiresAt
+		}
+
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,98 @@
+package baninfo
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type Response struct {
+	response.Response
+	Id         int64      `json:"id"`
+	Reason     string     `json:"reason"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Appeal     string     `json:"appeal,omitempty"`
+	AppealedAt *time.Time `json:"appealed_at,omitempty"`
+}
+
+type BanProvider interface {
+	User(login string) (*models.User, error)
+	ActiveBan(userID int64) (*models.Ban, error)
+}
+
+// New creates a new HandlerFunc for showing the ban of a user in effect along with their appeal
+func New(log *slog.Logger, banProvider BanProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.baninfo.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		login := chi.URLParam(r, "login")
+
+		user, err := banProvider.User(login)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("user", login))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("user not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		ban, err := banProvider.ActiveBan(user.Id)
+		if errors.Is(err, storage.ErrBanNotFound) {
+			log.Info("user is not banned", slog.String("user", login))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("user is not banned"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding ban", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		resp := Response{
+			Response:  response.OK(),
+			Id:        ban.Id,
+			Reason:    ban.Reason,
+			CreatedAt: ban.CreatedAt,
+			Appeal:    ban.Appeal,
+		}
+
+		if !ban.ExpiresAt.IsZero() {
+			resp.ExpiresAt = &ban.ExpiresAt
+		}
+		if !ban.AppealedAt.IsZero() {
+			resp.AppealedAt = &ban.AppealedAt
+		}
+
+		render.JSON(w, r, resp)
+	}
+}
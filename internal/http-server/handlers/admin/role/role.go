@@ -0,0 +1,142 @@
+package role
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/rbac"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type RoleSetter interface {
+	User(login string) (*models.User, error)
+	SetUserRole(login string, role models.Role) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for changing role of a user by admin
+// only roles of users with lower role can be changed, and not above the admin's own role
+func New(log *slog.Logger, roleSetter RoleSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.role.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+		login := chi.URLParam(r, "login")
+
+		// admins can't change their own role, so that there's always an admin left
+		if claims.Login == login {
+			log.Info("attempt to change own role")
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("can't change own role"))
+
+			return
+		}
+
+		var req request.RoleRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		role := models.Role(req.Role)
+		if !rbac.ValidRole(role) {
+			log.Info("unknown role", slog.String("role", req.Role))
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("unknown role"))
+
+			return
+		}
+
+		// role can't be granted above own one
+		if rbac.Outranks(role, claims.Role) {
+			log.Info("attempt to grant higher role", slog.String("role", req.Role))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("can't grant role higher than own"))
+
+			return
+		}
+
+		target, err := roleSetter.User(login)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("user", login))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("user not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// same rule as for bans: users of the same or higher role are out of reach
+		if !rbac.Outranks(claims.Role, target.Role) {
+			log.Info("attempt to change role of user with the same or higher role", slog.String("user", login))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("can't change role of user with the same or higher role"))
+
+			return
+		}
+
+		err = roleSetter.SetUserRole(login, role)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("user", login))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("user not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to change role", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("role changed", slog.String("user", login), slog.String("role", req.Role))
+
+		audit.Record(log, roleSetter, r, models.AuditEntry{
+			Event:      models.AuditRoleChange,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     "user:" + login,
+			Details:    "role=" + req.Role,
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,176 @@
+package role_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/admin/role"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/jwt"
+	"github.com/rigbyel/ad-market/internal/lib/rbac"
+	"github.com/rigbyel/ad-market/internal/lib/session"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+// service runs role endpoint on a fresh storage
+type service struct {
+	storage *storage.Storage
+	tokens  *jwt.Manager
+	router  http.Handler
+}
+
+func newService(t *testing.T) *service {
+	t.Helper()
+
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+
+	m, err := migrate.New("file://../../../../../migrations", "sqlite3://"+storagePath)
+	if err != nil {
+		t.Fatalf("failed to prepare migrations: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+	m.Close()
+
+	st, err := storage.New(storagePath)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	t.Cleanup(func() { st.Stop() })
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tokens := jwt.NewHMACManager(jwt.Options{}, "secret")
+	authMiddleware := auth.New(log, tokens, false, st)
+
+	router := chi.NewRouter()
+	router.With(authMiddleware.RequireAuth, authMiddleware.RequirePermission(rbac.PermManageRoles)).
+		Put("/admin/users/{login}/role", role.New(log, st))
+
+	return &service{storage: st, tokens: tokens, router: router}
+}
+
+// registers user with the role and returns their access token
+func (s *service) user(t *testing.T, login string, r models.Role) string {
+	t.Helper()
+
+	user, err := s.storage.SaveUser(&models.User{
+		Login:    login,
+		LoginKey: login,
+		PassHash: []byte{},
+		RegDate:  time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+
+	if err := s.storage.SetUserRole(login, r); err != nil {
+		t.Fatalf("failed to set role: %v", err)
+	}
+	user.Role = r
+
+	issued, err := session.Start(s.storage, *user, httptest.NewRequest(http.MethodPost, "/login", nil), s.tokens, time.Minute, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to start session: %v", err)
+	}
+
+	return issued.AccessToken
+}
+
+// changes role of the user with login on behalf of the owner of token
+func (s *service) setRole(t *testing.T, token, login string, r models.Role) int {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/users/"+login+"/role", strings.NewReader(`{"role":"`+string(r)+`"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+// returns current role of the user
+func (s *service) role(t *testing.T, login string) models.Role {
+	t.Helper()
+
+	user, err := s.storage.User(login)
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+
+	return user.Role
+}
+
+func TestAdminCantDemoteAdmin(t *testing.T) {
+	s := newService(t)
+
+	token := s.user(t, "alice", models.RoleAdmin)
+	s.user(t, "bob", models.RoleAdmin)
+
+	if code := s.setRole(t, token, "bob", models.RoleUser); code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", code)
+	}
+
+	if r := s.role(t, "bob"); r != models.RoleAdmin {
+		t.Fatalf("role of admin changed to %s", r)
+	}
+}
+
+func TestSetRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		target models.Role
+		role   models.Role
+		want   int
+	}{
+		{"promote user to moderator", models.RoleUser, models.RoleModerator, http.StatusOK},
+		{"promote user to admin", models.RoleUser, models.RoleAdmin, http.StatusOK},
+		{"demote moderator", models.RoleModerator, models.RoleUser, http.StatusOK},
+		{"demote admin", models.RoleAdmin, models.RoleModerator, http.StatusForbidden},
+		{"unknown role", models.RoleUser, models.Role("owner"), http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(t)
+
+			token := s.user(t, "alice", models.RoleAdmin)
+			s.user(t, "bob", tt.target)
+
+			if code := s.setRole(t, token, "bob", tt.role); code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, code)
+			}
+
+			want := tt.target
+			if tt.want == http.StatusOK {
+				want = tt.role
+			}
+
+			if r := s.role(t, "bob"); r != want {
+				t.Fatalf("expected role %s, got %s", want, r)
+			}
+		})
+	}
+}
+
+func TestAdminCantChangeOwnRole(t *testing.T) {
+	s := newService(t)
+
+	token := s.user(t, "alice", models.RoleAdmin)
+
+	if code := s.setRole(t, token, "alice", models.RoleUser); code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", code)
+	}
+}
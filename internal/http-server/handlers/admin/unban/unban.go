@@ -0,0 +1,86 @@
+package unban
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type BanLifter interface {
+	User(login string) (*models.User, error)
+	LiftBans(userID int64, at time.Time) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for lifting bans of a user
+func New(log *slog.Logger, banLifter BanLifter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.unban.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+		login := chi.URLParam(r, "login")
+
+		user, err := banLifter.User(login)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("user", login))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("user not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		err = banLifter.LiftBans(user.Id, time.Now())
+		if errors.Is(err, storage.ErrBanNotFound) {
+			log.Info("user is not banned", slog.String("user", login))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("user is not banned"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to lift ban", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("ban lifted", slog.String("user", login))
+
+		audit.Record(log, banLifter, r, models.AuditEntry{
+			Event:      models.AuditUserUnban,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     "user:" + login,
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -1,14 +1,15 @@
 package create
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
-	"github.com/rigbyel/ad-market/internal/lib/jwt"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
 	"github.com/rigbyel/ad-market/internal/lib/request"
 	"github.com/rigbyel/ad-market/internal/lib/response"
 	"github.com/rigbyel/ad-market/internal/lib/validate"
@@ -20,44 +21,37 @@ type Response struct {
 	Id          int64     `json:"id"`
 	AuthorLogin string    `json:"author_id"`
 	Date        time.Time `json:"date"`
+	AdStatus    string    `json:"advert_status"`
 }
 
 type AdSaver interface {
 	SaveAd(ad *models.Advert) (*models.Advert, error)
+	audit.Saver
 }
 
 // New creates a new HandlerFunc for handling advert creation
-func New(log *slog.Logger, adSaver AdSaver, authSecret string) http.HandlerFunc {
+func New(log *slog.Logger, adSaver AdSaver) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.advert.create.New"
 
 		// setting up logger
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// authorization via jwt token
-		tokenString := r.Header.Get("Authorization-access")
-		tokenClaims, err := jwt.GetTokenClaims(tokenString, authSecret)
-		if err != nil {
-			log.Error("authorization failed", slog.String("error", err.Error()))
-
-			render.JSON(w, r, response.Error("authorization failed"))
-
-			return
-		}
-
-		// getting user's login
-		login := tokenClaims.Login
+		// getting authorized user
+		claims, _ := auth.UserClaims(r.Context())
+		login := claims.Login
 
 		var req request.AdvertRequest
 
 		// decoding request
-		err = render.DecodeJSON(r.Body, &req)
+		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
 			log.Error("failed to decode request body", slog.String("error", err.Error()))
 
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error("failed to decode request body"))
 
 			return
@@ -65,16 +59,26 @@ func New(log *slog.Logger, adSaver AdSaver, authSecret string) http.HandlerFunc
 
 		log.Info("request body decoded", slog.Any("request", req))
 
+		req.Header = validate.NormalizeText(req.Header)
+		req.Body = validate.NormalizeText(req.Body)
+
 		// validating advert data from user request
 		validationErrs := validate.ValidateAdvert(req)
 		if len(validationErrs) != 0 {
 			log.Error("invalid request")
 
-			render.JSON(w, r, response.Error(strings.Join(validationErrs, ", ")))
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Invalid(validationErrs))
 
 			return
 		}
 
+		// adverts are published right away unless created as drafts
+		status := req.Status
+		if status == "" {
+			status = models.AdvertStatusActive
+		}
+
 		// creating and saving advert
 		ad := &models.Advert{
 			Header:      req.Header,
@@ -83,12 +87,14 @@ func New(log *slog.Logger, adSaver AdSaver, authSecret string) http.HandlerFunc
 			Price:       req.Price,
 			Date:        time.Now(),
 			AuthorLogin: login,
+			Status:      status,
 		}
 
 		ad, err = adSaver.SaveAd(ad)
 		if err != nil {
 			log.Error("error saving advert", slog.String("error", err.Error()))
 
+			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, response.Error("error saving advert"))
 
 			return
@@ -96,11 +102,21 @@ func New(log *slog.Logger, adSaver AdSaver, authSecret string) http.HandlerFunc
 
 		log.Info("advert saved")
 
+		audit.Record(log, adSaver, r, models.AuditEntry{
+			Event:      models.AuditAdvertCreate,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     fmt.Sprintf("advert:%d", ad.Id),
+			Details:    "status=" + ad.Status,
+		})
+
+		render.Status(r, http.StatusCreated)
 		render.JSON(w, r, Response{
 			Response:    response.OK(),
 			Id:          ad.Id,
 			Date:        ad.Date,
 			AuthorLogin: login,
+			AdStatus:    ad.Status,
 		})
 	}
 }
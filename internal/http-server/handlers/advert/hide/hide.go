@@ -0,0 +1,77 @@
+package hide
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type AdHider interface {
+	SetAdvertStatus(id int64, status string) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for hiding advert from feed by moderator
+func New(log *slog.Logger, adHider AdHider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.advert.hide.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid advert id", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid advert id"))
+
+			return
+		}
+
+		err = adHider.SetAdvertStatus(id, models.AdvertStatusHidden)
+		if errors.Is(err, storage.ErrAdvertNotFound) {
+			log.Info("advert not found", slog.Int64("id", id))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("advert not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to hide advert", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("advert hidden", slog.Int64("id", id))
+
+		audit.Record(log, adHider, r, models.AuditEntry{
+			Event:      models.AuditAdvertHide,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     fmt.Sprintf("advert:%d", id),
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
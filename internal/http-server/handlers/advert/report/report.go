@@ -0,0 +1,167 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type ReportSaver interface {
+	Advert(id int64) (*models.Advert, error)
+	SaveReport(rep *models.Report) (*models.Report, error)
+	OpenReportsCount(advertID int64) (int, error)
+	SetAdvertStatus(id int64, status string) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for reporting an advert
+// advert is hidden from feed once it gets reportThreshold reports
+func New(log *slog.Logger, reportSaver ReportSaver, reportThreshold int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.advert.report.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid advert id", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid advert id"))
+
+			return
+		}
+
+		var req request.ReportRequest
+
+		// decoding request
+		err = render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		if !models.ReportReasons[models.ReportReason(req.Reason)] {
+			log.Info("unknown report reason", slog.String("reason", req.Reason))
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("reason should be one of: spam, scam, prohibited, offensive, other"))
+
+			return
+		}
+
+		req.Comment = validate.NormalizeText(req.Comment)
+
+		if validate.TextLength(req.Comment) > constraints.ReportCommentMaxLen {
+			log.Info("report comment is too long")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("comment is too long"))
+
+			return
+		}
+
+		// only adverts visible in feed can be reported
+		ad, err := reportSaver.Advert(id)
+		if errors.Is(err, storage.ErrAdvertNotFound) || (err == nil && ad.Status != models.AdvertStatusActive) {
+			log.Info("advert not found", slog.Int64("id", id))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("advert not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding advert", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		if ad.AuthorLogin == claims.Login {
+			log.Info("attempt to report own advert")
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("can't report own advert"))
+
+			return
+		}
+
+		_, err = reportSaver.SaveReport(&models.Report{
+			AdvertId:   ad.Id,
+			ReporterId: claims.ID,
+			Reason:     models.ReportReason(req.Reason),
+			Comment:    req.Comment,
+			CreatedAt:  time.Now(),
+		})
+		if errors.Is(err, storage.ErrAlreadyReported) {
+			log.Info("advert already reported by user")
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("advert already reported"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error saving report", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("advert reported", slog.Int64("id", ad.Id), slog.String("reason", req.Reason))
+
+		audit.Record(log, reportSaver, r, models.AuditEntry{
+			Event:      models.AuditAdvertReport,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     fmt.Sprintf("advert:%d", ad.Id),
+			Details:    "reason=" + req.Reason,
+		})
+
+		// hiding advert until moderator reviews it if there are too many reports
+		count, err := reportSaver.OpenReportsCount(ad.Id)
+		if err != nil {
+			log.Error("failed to count reports", slog.String("error", err.Error()))
+		}
+
+		if err == nil && count >= reportThreshold {
+			if err := reportSaver.SetAdvertStatus(ad.Id, models.AdvertStatusHidden); err != nil {
+				log.Error("failed to hide advert", slog.String("error", err.Error()))
+			} else {
+				log.Info("advert hidden after reports", slog.Int64("id", ad.Id), slog.Int("reports", count))
+			}
+		}
+
+		render.JSON(w, r, response.OK())
+	}
+}
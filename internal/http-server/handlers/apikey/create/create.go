@@ -0,0 +1,194 @@
+package create
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/apikey"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/rbac"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+)
+
+type Response struct {
+	response.Response
+	Id     int64  `json:"id"`
+	Prefix string `json:"prefix"`
+
+	// the key is shown only once
+	Key string `json:"key"`
+
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type APIKeySaver interface {
+	UserAPIKeys(userID int64) ([]models.APIKey, error)
+	SaveAPIKey(key *models.APIKey) (*models.APIKey, error)
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for creating API key of the authorized user
+func New(log *slog.Logger, apiKeySaver APIKeySaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.create.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		var req request.APIKeyRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		log.Info("request body decoded", slog.String("name", req.Name), slog.Any("scopes", req.Scopes))
+
+		req.Name = validate.NormalizeText(strings.TrimSpace(req.Name))
+
+		// validating key parameters
+		validationErrs := validate.Errors{}
+
+		if req.Name == "" {
+			validationErrs = append(validationErrs, validate.FieldError{
+				Field: "name", Code: validate.CodeRequired, Message: "name is required",
+			})
+		}
+
+		if validate.TextLength(req.Name) > constraints.APIKeyNameMaxLen {
+			validationErrs = append(validationErrs, validate.FieldError{
+				Field: "name", Code: validate.CodeTooLong, Message: fmt.Sprintf("name should be at most %d characters", constraints.APIKeyNameMaxLen),
+			})
+		}
+
+		if len(req.Scopes) == 0 {
+			validationErrs = append(validationErrs, validate.FieldError{
+				Field: "scopes", Code: validate.CodeRequired, Message: "at least one scope is required",
+			})
+		}
+
+		for _, scope := range req.Scopes {
+			if !rbac.ValidScope(rbac.Scope(scope)) {
+				validationErrs = append(validationErrs, validate.FieldError{
+					Field: "scopes", Code: validate.CodeInvalid, Message: "unknown scope: " + scope,
+				})
+			}
+		}
+
+		var expiresIn time.Duration
+		if req.ExpiresIn != "" {
+			expiresIn, err = time.ParseDuration(req.ExpiresIn)
+			if err != nil || expiresIn <= 0 {
+				validationErrs = append(validationErrs, validate.FieldError{
+					Field: "expires_in", Code: validate.CodeInvalid, Message: "expires_in should be a positive duration, e.g. 720h",
+				})
+			}
+		}
+
+		if len(validationErrs) != 0 {
+			log.Info("invalid api key parameters")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Invalid(validationErrs))
+
+			return
+		}
+
+		keys, err := apiKeySaver.UserAPIKeys(claims.ID)
+		if err != nil {
+			log.Error("failed to get api keys", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		if len(keys) >= constraints.APIKeysMaxCount {
+			log.Info("too many api keys")
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("too many api keys, revoke unused ones"))
+
+			return
+		}
+
+		key, prefix, hash, err := apikey.New()
+		if err != nil {
+			log.Error("failed to generate api key", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		now := time.Now()
+
+		stored := &models.APIKey{
+			UserId:    claims.ID,
+			Name:      req.Name,
+			Prefix:    prefix,
+			KeyHash:   hash,
+			Scopes:    req.Scopes,
+			CreatedAt: now,
+		}
+		if expiresIn > 0 {
+			stored.ExpiresAt = now.Add(expiresIn)
+		}
+
+		stored, err = apiKeySaver.SaveAPIKey(stored)
+		if err != nil {
+			log.Error("failed to save api key", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("api key created", slog.String("prefix", prefix))
+
+		audit.Record(log, apiKeySaver, r, models.AuditEntry{
+			Event:      models.AuditAPIKeyCreate,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     "api-key:" + prefix,
+			Details:    "scopes=" + strings.Join(req.Scopes, ","),
+		})
+
+		resp := Response{
+			Response: response.OK(),
+			Id:       stored.Id,
+			Prefix:   prefix,
+			Key:      key,
+		}
+		if !stored.ExpiresAt.IsZero() {
+			resp.ExpiresAt = &stored.ExpiresAt
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, resp)
+	}
+}
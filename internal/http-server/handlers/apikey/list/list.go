@@ -0,0 +1,89 @@
+package list
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type APIKey struct {
+	Id         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Expired    bool       `json:"expired,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	APIKeys []APIKey `json:"api_keys"`
+}
+
+type APIKeyProvider interface {
+	UserAPIKeys(userID int64) ([]models.APIKey, error)
+}
+
+// New creates a new HandlerFunc for listing API keys of the authorized user
+func New(log *slog.Logger, apiKeyProv APIKeyProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.list.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		keys, err := apiKeyProv.UserAPIKeys(claims.ID)
+		if err != nil {
+			log.Error("failed to get api keys", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		resp := Response{
+			Response: response.OK(),
+			APIKeys:  []APIKey{},
+		}
+
+		now := time.Now()
+
+		for _, k := range keys {
+			key := APIKey{
+				Id:        k.Id,
+				Name:      k.Name,
+				Prefix:    k.Prefix,
+				Scopes:    k.Scopes,
+				CreatedAt: k.CreatedAt,
+			}
+
+			if !k.ExpiresAt.IsZero() {
+				key.ExpiresAt = &k.ExpiresAt
+				key.Expired = now.After(k.ExpiresAt)
+			}
+			if !k.LastUsedAt.IsZero() {
+				key.LastUsedAt = &k.LastUsedAt
+			}
+
+			resp.APIKeys = append(resp.APIKeys, key)
+		}
+
+		log.Info("api keys accessed")
+
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,79 @@
+package revoke
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type APIKeyRevoker interface {
+	RevokeAPIKey(userID, id int64, at time.Time) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for revoking API key of the authorized user
+// the key stops working immediately
+func New(log *slog.Logger, apiKeyRevoker APIKeyRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikey.revoke.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid api key id", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid api key id"))
+
+			return
+		}
+
+		// keys of other users are reported as not found
+		err = apiKeyRevoker.RevokeAPIKey(claims.ID, id, time.Now())
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Info("api key not found", slog.Int64("id", id))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("api key not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to revoke api key", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("api key revoked", slog.Int64("id", id))
+
+		audit.Record(log, apiKeyRevoker, r, models.AuditEntry{
+			Event:      models.AuditAPIKeyRevoke,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     "api-key:" + strconv.FormatInt(id, 10),
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,97 @@
+package verify
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/emailverify"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type EmailVerifier interface {
+	VerifyEmail(userID int64, email string, at time.Time) error
+	UserByID(id int64) (*models.User, error)
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for confirming email by the link sent to it
+func New(log *slog.Logger, emailVerifier EmailVerifier, verifier *emailverify.Verifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.email.verify.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// checking signature and lifetime of the link
+		claims, err := verifier.Check(r.URL.Query().Get("token"), time.Now())
+		if errors.Is(err, emailverify.ErrExpiredToken) {
+			log.Info("verification link expired")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("verification link expired"))
+
+			return
+		}
+		if err != nil {
+			log.Info("invalid verification link", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid verification link"))
+
+			return
+		}
+
+		// link is only valid for the email it was sent to
+		err = emailVerifier.VerifyEmail(claims.UserID, claims.Email, time.Now())
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("email was changed after link was sent", slog.Int64("user", claims.UserID))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid verification link"))
+
+			return
+		}
+		// only the owner of the address gets this link, so the conflict isn't a secret for them
+		if errors.Is(err, storage.ErrEmailExists) {
+			log.Info("email is verified by another user", slog.Int64("user", claims.UserID))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("email is already verified by another account"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to verify email", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("email verified", slog.Int64("user", claims.UserID))
+
+		entry := models.AuditEntry{
+			Event:   models.AuditEmailVerify,
+			ActorId: claims.UserID,
+		}
+		if user, err := emailVerifier.UserByID(claims.UserID); err == nil {
+			entry.ActorLogin = user.Login
+			entry.Target = "user:" + user.Login
+		}
+
+		audit.Record(log, emailVerifier, r, entry)
+
+		render.JSON(w, r, response.OK())
+	}
+}
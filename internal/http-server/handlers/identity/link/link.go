@@ -0,0 +1,97 @@
+package link
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/oidc"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type Response struct {
+	response.Response
+
+	// address the user should open in browser to authorize at the provider
+	AuthURL string `json:"auth_url"`
+}
+
+type StateSaver interface {
+	SaveOIDCState(state *models.OIDCState) (*models.OIDCState, error)
+}
+
+// New creates a new HandlerFunc starting linking of external provider account to the authorized user
+// identity is linked when the user returns from the provider to callback within stateTL
+func New(log *slog.Logger, stateSaver StateSaver, providers oidc.Providers, stateTL time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.identity.link.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		name := chi.URLParam(r, "provider")
+
+		provider, ok := providers[name]
+		if !ok {
+			log.Info("unknown provider", slog.String("provider", name))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("unknown provider"))
+
+			return
+		}
+
+		authReq, err := provider.NewAuthRequest(r.Context())
+		if err != nil {
+			log.Error("failed to start authorization", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadGateway)
+			render.JSON(w, r, response.Error("provider is unavailable"))
+
+			return
+		}
+
+		now := time.Now()
+
+		_, err = stateSaver.SaveOIDCState(&models.OIDCState{
+			StateHash: opaque.Hash(authReq.State),
+			Provider:  name,
+			Nonce:     authReq.Nonce,
+			Verifier:  authReq.Verifier,
+			UserId:    claims.ID,
+			CreatedAt: now,
+			ExpiresAt: now.Add(stateTL),
+		})
+		if err != nil {
+			log.Error("failed to save authorization request", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// linking has to be finished in the browser that started it
+		oidc.SetStateCookie(w, r, name, authReq.State, stateTL)
+
+		log.Info("identity linking started", slog.String("provider", name))
+
+		render.JSON(w, r,
+			Response{
+				Response: response.OK(),
+				AuthURL:  authReq.URL,
+			},
+		)
+	}
+}
@@ -0,0 +1,70 @@
+package list
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type Identity struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Response struct {
+	response.Response
+	Identities []Identity `json:"identities"`
+}
+
+type IdentityProvider interface {
+	UserIdentities(userID int64) ([]models.Identity, error)
+}
+
+// New creates a new HandlerFunc for listing external provider accounts linked to the authorized user
+func New(log *slog.Logger, identityProv IdentityProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.identity.list.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		identities, err := identityProv.UserIdentities(claims.ID)
+		if err != nil {
+			log.Error("failed to get identities", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		resp := Response{
+			Response:   response.OK(),
+			Identities: []Identity{},
+		}
+
+		for _, i := range identities {
+			resp.Identities = append(resp.Identities, Identity{
+				Provider:  i.Provider,
+				Email:     i.Email,
+				CreatedAt: i.CreatedAt,
+			})
+		}
+
+		log.Info("identities accessed")
+
+		render.JSON(w, r, resp)
+	}
+}
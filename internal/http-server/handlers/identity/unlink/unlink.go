@@ -0,0 +1,100 @@
+package unlink
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type IdentityDeleter interface {
+	UserByID(id int64) (*models.User, error)
+	UserIdentities(userID int64) ([]models.Identity, error)
+	DeleteIdentity(userID int64, provider string) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for unlinking external provider account from the authorized user
+// the last identity of user without password can't be unlinked, they couldn't log in otherwise
+func New(log *slog.Logger, identityDeleter IdentityDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.identity.unlink.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		provider := chi.URLParam(r, "provider")
+
+		user, err := identityDeleter.UserByID(claims.ID)
+		if err != nil {
+			log.Error("failed to get user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		identities, err := identityDeleter.UserIdentities(claims.ID)
+		if err != nil {
+			log.Error("failed to get identities", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		if len(user.PassHash) == 0 && len(identities) == 1 && identities[0].Provider == provider {
+			log.Info("last identity of user without password")
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("set a password before unlinking the last provider"))
+
+			return
+		}
+
+		err = identityDeleter.DeleteIdentity(claims.ID, provider)
+		if errors.Is(err, storage.ErrIdentityNotFound) {
+			log.Info("identity not found", slog.String("provider", provider))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("provider is not linked"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to unlink identity", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("identity unlinked", slog.String("provider", provider))
+
+		audit.Record(log, identityDeleter, r, models.AuditEntry{
+			Event:      models.AuditIdentityUnlink,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     "user:" + claims.Login,
+			Details:    "provider=" + provider,
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
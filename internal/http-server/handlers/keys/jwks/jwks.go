@@ -0,0 +1,34 @@
+package jwks
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/jwt"
+)
+
+type KeyProvider interface {
+	JWKS() jwt.JWKS
+}
+
+// New creates a new HandlerFunc publishing public keys for verifying tokens
+func New(log *slog.Logger, keyProv KeyProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.keys.jwks.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		log.Debug("jwks accessed")
+
+		// verifiers may cache keys, retired keys stay published while their tokens live
+		w.Header().Set("Cache-Control", "public, max-age=300")
+
+		render.JSON(w, r, keyProv.JWKS())
+	}
+}
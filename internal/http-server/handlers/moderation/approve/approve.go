@@ -0,0 +1,78 @@
+package approve
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type AdModerator interface {
+	ModerateAdvert(id int64, status, reason string) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for approving reported advert
+// reports are dismissed and advert gets back to feed
+func New(log *slog.Logger, adModerator AdModerator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.moderation.approve.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid advert id", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid advert id"))
+
+			return
+		}
+
+		err = adModerator.ModerateAdvert(id, models.AdvertStatusActive, "")
+		if errors.Is(err, storage.ErrAdvertNotFound) {
+			log.Info("advert not found", slog.Int64("id", id))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("advert not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to approve advert", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("advert approved", slog.Int64("id", id))
+
+		audit.Record(log, adModerator, r, models.AuditEntry{
+			Event:      models.AuditModerationApprove,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     fmt.Sprintf("advert:%d", id),
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
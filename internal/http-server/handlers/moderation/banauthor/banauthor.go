@@ -0,0 +1,170 @@
+package banauthor
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/rbac"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type AuthorBanner interface {
+	Advert(id int64) (*models.Advert, error)
+	User(login string) (*models.User, error)
+	ModerateAdvert(id int64, status, reason string) error
+	SaveBan(ban *models.Ban) (*models.Ban, error)
+	RevokeUserSessions(userID int64) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for rejecting reported advert and banning its author
+func New(log *slog.Logger, authorBanner AuthorBanner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.moderation.banauthor.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid advert id", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid advert id"))
+
+			return
+		}
+
+		var req request.ModerationRequest
+
+		// decoding request
+		err = render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		req.Reason = validate.NormalizeText(req.Reason)
+
+		if req.Reason == "" || validate.TextLength(req.Reason) > constraints.ModerationReasonMaxLen {
+			log.Info("invalid ban reason")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("reason is required and should be shorter than 500 characters"))
+
+			return
+		}
+
+		ad, err := authorBanner.Advert(id)
+		if errors.Is(err, storage.ErrAdvertNotFound) {
+			log.Info("advert not found", slog.Int64("id", id))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("advert not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding advert", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		author, err := authorBanner.User(ad.AuthorLogin)
+		if err != nil {
+			log.Error("error finding author", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		if author.Id == claims.ID {
+			log.Info("attempt to ban self")
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("can't ban yourself"))
+
+			return
+		}
+
+		// moderators can't ban each other or admins
+		if !rbac.Outranks(claims.Role, author.Role) {
+			log.Info("attempt to ban user of same or higher role", slog.String("author", author.Login))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("can't ban user with the same or higher role"))
+
+			return
+		}
+
+		if err := authorBanner.ModerateAdvert(ad.Id, models.AdvertStatusRejected, req.Reason); err != nil {
+			log.Error("failed to reject advert", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// banning author permanently
+		_, err = authorBanner.SaveBan(&models.Ban{
+			UserId:    author.Id,
+			Reason:    req.Reason,
+			CreatedAt: time.Now(),
+			CreatedBy: claims.ID,
+		})
+		if err != nil {
+			log.Error("failed to ban author", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// logging banned author out everywhere
+		if err := authorBanner.RevokeUserSessions(author.Id); err != nil {
+			log.Error("failed to revoke sessions of banned author", slog.String("error", err.Error()))
+		}
+
+		log.Info("advert rejected and author banned", slog.Int64("id", ad.Id), slog.String("author", author.Login))
+
+		audit.Record(log, authorBanner, r, models.AuditEntry{
+			Event:      models.AuditModerationBanAuthor,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     "user:" + author.Login,
+			Details:    fmt.Sprintf("advert=%d reason=%s", ad.Id, req.Reason),
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type Item struct {
+	Id           int64                 `json:"id"`
+	Header       string                `json:"header"`
+	Body         string                `json:"body"`
+	ImageURL     string                `json:"image_url,omitempty"`
+	Price        int                   `json:"price"`
+	Date         time.Time             `json:"date"`
+	Author       string                `json:"author"`
+	Status       string                `json:"status"`
+	ReportsCount int                   `json:"reports_count"`
+	Reasons      []models.ReportReason `json:"reasons"`
+}
+
+type Response struct {
+	response.Response
+	Adverts []Item `json:"adverts"`
+}
+
+type QueueProvider interface {
+	ModerationQueue() ([]models.ModerationItem, error)
+}
+
+// New creates a new HandlerFunc for showing reported adverts waiting for moderation
+func New(log *slog.Logger, queueProv QueueProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.moderation.queue.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		items, err := queueProv.ModerationQueue()
+		if err != nil {
+			log.Error("failed to get moderation queue", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		resp := Response{
+			Response: response.OK(),
+			Adverts:  []Item{},
+		}
+
+		for _, item := range items {
+			resp.Adverts = append(resp.Adverts, Item{
+				Id:           item.Advert.Id,
+				Header:       item.Advert.Header,
+				Body:         item.Advert.Body,
+				ImageURL:     item.Advert.ImageURL,
+				Price:        item.Advert.Price,
+				Date:         item.Advert.Date,
+				Author:       item.Advert.AuthorLogin,
+				Status:       item.Advert.Status,
+				ReportsCount: item.ReportsCount,
+				Reasons:      item.Reasons,
+			})
+		}
+
+		log.Info("moderation queue accessed")
+
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,106 @@
+package reject
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type AdModerator interface {
+	ModerateAdvert(id int64, status, reason string) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for rejecting reported advert
+// the reason is shown to the author of the advert
+func New(log *slog.Logger, adModerator AdModerator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.moderation.reject.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid advert id", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid advert id"))
+
+			return
+		}
+
+		var req request.ModerationRequest
+
+		// decoding request
+		err = render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		req.Reason = validate.NormalizeText(req.Reason)
+
+		if req.Reason == "" || validate.TextLength(req.Reason) > constraints.ModerationReasonMaxLen {
+			log.Info("invalid rejection reason")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("reason is required and should be shorter than 500 characters"))
+
+			return
+		}
+
+		err = adModerator.ModerateAdvert(id, models.AdvertStatusRejected, req.Reason)
+		if errors.Is(err, storage.ErrAdvertNotFound) {
+			log.Info("advert not found", slog.Int64("id", id))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("advert not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to reject advert", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("advert rejected", slog.Int64("id", id))
+
+		audit.Record(log, adModerator, r, models.AuditEntry{
+			Event:      models.AuditModerationReject,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     fmt.Sprintf("advert:%d", id),
+			Details:    "reason=" + req.Reason,
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
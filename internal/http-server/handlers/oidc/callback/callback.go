@@ -0,0 +1,405 @@
+package callback
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/user/login"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/jwt"
+	"github.com/rigbyel/ad-market/internal/lib/oidc"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/session"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+// number of random suffixes tried when login made from the user's name is taken
+const loginAttempts = 5
+
+// LinkResponse confirms that identity is linked to the user who started linking
+type LinkResponse struct {
+	response.Response
+	Provider string `json:"provider"`
+}
+
+type UserProvider interface {
+	UserByID(id int64) (*models.User, error)
+	UserByLoginKey(key string) (*models.User, error)
+	SaveUserWithIdentity(u *models.User, identity *models.Identity) (*models.User, error)
+	Identity(provider, subject string) (*models.Identity, error)
+	SaveIdentity(identity *models.Identity) (*models.Identity, error)
+	OIDCState(stateHash string) (*models.OIDCState, error)
+	UseOIDCState(id int64, at time.Time) error
+	CreateSession(session *models.Session, rt *models.RefreshToken) error
+	ActiveBan(userID int64) (*models.Ban, error)
+	TOTP(userID int64) (*models.TOTP, error)
+	SaveMFAChallenge(c *models.MFAChallenge) (*models.MFAChallenge, error)
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for the endpoint the provider sends the user back to
+// identity of the user is linked to the account that started linking,
+// otherwise the user is logged in as the owner of the identity, which is created on first login
+// responses of successful login are the same as of /login
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	providers oidc.Providers,
+	loginPolicy *validate.LoginPolicy,
+	tokens *jwt.Manager,
+	tokenTL, refreshTL, mfaTL time.Duration,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.oidc.callback.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		name := chi.URLParam(r, "provider")
+
+		provider, ok := providers[name]
+		if !ok {
+			log.Info("unknown provider", slog.String("provider", name))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("unknown provider"))
+
+			return
+		}
+
+		q := r.URL.Query()
+
+		// the user declined or the provider failed to authorize them
+		if providerErr := q.Get("error"); providerErr != "" {
+			log.Info("provider returned error", slog.String("error", providerErr))
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("authorization failed at provider: "+providerErr))
+
+			return
+		}
+
+		// state must come back to the browser it was issued to, otherwise anyone could
+		// send their own callback link to the user and log them in to a foreign account
+		if !oidc.CheckStateCookie(w, r, name, q.Get("state")) {
+			log.Info("authorization request state isn't bound to the browser")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid or expired authorization request, start again"))
+
+			return
+		}
+
+		// finding authorization request by its state, it can be used once
+		state, err := userProvider.OIDCState(opaque.Hash(q.Get("state")))
+		if err != nil && !errors.Is(err, storage.ErrTokenNotFound) {
+			log.Error("failed to get authorization request", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if err != nil || state.Provider != name || !state.UsedAt.IsZero() || time.Now().After(state.ExpiresAt) {
+			log.Info("invalid authorization request state")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid or expired authorization request, start again"))
+
+			return
+		}
+
+		err = userProvider.UseOIDCState(state.Id, time.Now())
+		if errors.Is(err, storage.ErrTokenReused) {
+			log.Info("authorization request reused")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid or expired authorization request, start again"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to use authorization request", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// exchanging code for the user's identity
+		identity, err := provider.Exchange(r.Context(), q.Get("code"), state.Verifier, state.Nonce)
+		if errors.Is(err, oidc.ErrDiscovery) {
+			log.Error("provider is unavailable", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadGateway)
+			render.JSON(w, r, response.Error("provider is unavailable"))
+
+			return
+		}
+		if err != nil {
+			log.Info("failed to get identity", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("authorization failed"))
+
+			return
+		}
+
+		if state.UserId != 0 {
+			link(log, w, r, userProvider, state.UserId, identity)
+
+			return
+		}
+
+		// finding owner of the identity or registering them
+		user, err := identityOwner(userProvider, identity)
+		if errors.Is(err, storage.ErrIdentityNotFound) {
+			user, err = register(userProvider, loginPolicy, identity)
+			if errors.Is(err, storage.ErrEmailExists) {
+				log.Info("email of new identity is used by another account", slog.String("provider", name))
+
+				render.Status(r, response.StatusOf(err))
+				render.JSON(w, r, response.Error("account with this email already exists, log in and link the provider to it"))
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to register user", slog.String("error", err.Error()))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, response.Error("internal error"))
+
+				return
+			}
+
+			log.Info("user registered with provider", slog.Int64("id", user.Id), slog.String("provider", name))
+
+			audit.Record(log, userProvider, r, models.AuditEntry{
+				Event:      models.AuditRegister,
+				ActorId:    user.Id,
+				ActorLogin: user.Login,
+				Target:     "user:" + user.Login,
+				Details:    "provider=" + name,
+			})
+		}
+		if err != nil {
+			log.Error("failed to find user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// banned users can't log in
+		if login.RejectBanned(log, w, r, userProvider, user) {
+			return
+		}
+
+		// provider replaces password, but not the second factor
+		if login.ChallengeSecondFactor(log, w, r, userProvider, user, mfaTL) {
+			return
+		}
+
+		log.Info("user logged in with provider", slog.String("provider", name))
+
+		// starting new session with its access and refresh tokens
+		issued, err := session.Start(userProvider, *user, r, tokens, tokenTL, refreshTL)
+		if err != nil {
+			log.Error("failed to start session", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		audit.Record(log, userProvider, r, models.AuditEntry{
+			Event:      models.AuditLoginSuccess,
+			ActorId:    user.Id,
+			ActorLogin: user.Login,
+			Target:     "user:" + user.Login,
+			Details:    "session=" + issued.SessionID + " provider=" + name,
+		})
+
+		render.JSON(w, r,
+			login.Response{
+				Response:     response.OK(),
+				Login:        user.Login,
+				Id:           user.Id,
+				Token:        issued.AccessToken,
+				RefreshToken: issued.RefreshToken,
+			},
+		)
+	}
+}
+
+// links identity to the user who started linking
+func link(log *slog.Logger, w http.ResponseWriter, r *http.Request, userProvider UserProvider, userID int64, identity *oidc.Identity) {
+	user, err := userProvider.UserByID(userID)
+	if err != nil {
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("internal error"))
+
+		return
+	}
+
+	_, err = userProvider.SaveIdentity(&models.Identity{
+		UserId:    user.Id,
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: time.Now(),
+	})
+	if errors.Is(err, storage.ErrIdentityExists) {
+		log.Info("identity already linked", slog.String("provider", identity.Provider))
+
+		render.Status(r, response.StatusOf(err))
+		render.JSON(w, r, response.Error("the provider account is linked to another user or this provider is already linked"))
+
+		return
+	}
+	if err != nil {
+		log.Error("failed to link identity", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("internal error"))
+
+		return
+	}
+
+	log.Info("identity linked", slog.String("provider", identity.Provider))
+
+	audit.Record(log, userProvider, r, models.AuditEntry{
+		Event:      models.AuditIdentityLink,
+		ActorId:    user.Id,
+		ActorLogin: user.Login,
+		Target:     "user:" + user.Login,
+		Details:    "provider=" + identity.Provider,
+	})
+
+	render.JSON(w, r,
+		LinkResponse{
+			Response: response.OK(),
+			Provider: identity.Provider,
+		},
+	)
+}
+
+// returns user the identity is linked to
+func identityOwner(userProvider UserProvider, identity *oidc.Identity) (*models.User, error) {
+	linked, err := userProvider.Identity(identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return userProvider.UserByID(linked.UserId)
+}
+
+// creates user without password for the identity
+// login is made from the user's name at the provider, a random suffix is added if it's taken
+// email is kept only if the provider has verified it
+func register(userProvider UserProvider, loginPolicy *validate.LoginPolicy, identity *oidc.Identity) (*models.User, error) {
+	base := baseLogin(identity)
+
+	for attempt := 0; attempt <= loginAttempts; attempt++ {
+		candidate := base
+		if attempt > 0 || len(candidate) < constraints.LoginMinLen {
+			suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+			if err != nil {
+				return nil, err
+			}
+
+			candidate = fmt.Sprintf("%s%04d", base, suffix.Int64())
+		}
+
+		if len(loginPolicy.Validate(candidate)) != 0 {
+			continue
+		}
+
+		_, err := userProvider.UserByLoginKey(validate.LoginKey(candidate))
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			return nil, err
+		}
+
+		now := time.Now()
+
+		user := &models.User{
+			Login:    candidate,
+			LoginKey: validate.LoginKey(candidate),
+			PassHash: []byte{},
+			RegDate:  now,
+		}
+		if identity.Email != "" && identity.EmailVerified {
+			user.Email = validate.NormalizeEmail(identity.Email)
+			user.EmailVerifiedAt = now
+		}
+
+		user, err = userProvider.SaveUserWithIdentity(user, &models.Identity{
+			Provider:  identity.Provider,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			CreatedAt: now,
+		})
+		if errors.Is(err, storage.ErrUserExists) {
+			// login was taken meanwhile
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return user, nil
+	}
+
+	return nil, fmt.Errorf("no free login for %q", base)
+}
+
+// makes login from preferred username, email or name of the user at the provider
+// only latin letters and digits are kept, so that the result is a valid login under any policy
+func baseLogin(identity *oidc.Identity) string {
+	name, _, _ := strings.Cut(identity.Email, "@")
+
+	for _, candidate := range []string{identity.PreferredUsername, name, identity.Name} {
+		login := strings.Map(func(r rune) rune {
+			if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+				return r
+			}
+
+			return -1
+		}, validate.NormalizeLogin(candidate))
+
+		// room is left for the suffix
+		if len(login) > constraints.LoginMaxLen-4 {
+			login = login[:constraints.LoginMaxLen-4]
+		}
+
+		if login != "" {
+			return login
+		}
+	}
+
+	return "user"
+}
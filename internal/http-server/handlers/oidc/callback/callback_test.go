@@ -0,0 +1,358 @@
+package callback_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/identity/link"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/oidc/callback"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/oidc/start"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/user/login"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/jwt"
+	"github.com/rigbyel/ad-market/internal/lib/oidc"
+	"github.com/rigbyel/ad-market/internal/lib/oidc/oidctest"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+// service runs login and linking endpoints on a fresh storage against two mock providers
+type service struct {
+	url      string
+	client   *http.Client
+	provider *oidctest.Provider
+	other    *oidctest.Provider
+}
+
+func newService(t *testing.T) *service {
+	t.Helper()
+
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+
+	m, err := migrate.New("file://../../../../../migrations", "sqlite3://"+storagePath)
+	if err != nil {
+		t.Fatalf("failed to prepare migrations: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+	m.Close()
+
+	st, err := storage.New(storagePath)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	t.Cleanup(func() { st.Stop() })
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tokens := jwt.NewHMACManager(jwt.Options{}, "secret")
+	providers := make(oidc.Providers)
+
+	router := chi.NewRouter()
+	router.Get("/oidc/{provider}/login", start.New(log, st, providers, time.Minute))
+	router.Get("/oidc/{provider}/callback", callback.New(
+		log, st, providers, &validate.LoginPolicy{}, tokens, time.Minute, time.Hour, time.Minute,
+	))
+	router.With(auth.New(log, tokens, false, st).RequireAuth).
+		Post("/me/identities/{provider}", link.New(log, st, providers, time.Minute))
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	s := &service{
+		url:      srv.URL,
+		client:   newBrowser(t),
+		provider: oidctest.New("client", "secret"),
+		other:    oidctest.New("other-client", "other-secret"),
+	}
+	t.Cleanup(s.provider.Close)
+	t.Cleanup(s.other.Close)
+
+	// providers are added after the service is started, since they need its callback address
+	providers["test"] = oidc.New(s.provider.Config("test", srv.URL+"/oidc/test/callback"), http.DefaultClient)
+	providers["other"] = oidc.New(s.other.Config("other", srv.URL+"/oidc/other/callback"), http.DefaultClient)
+
+	return s
+}
+
+// creates client keeping cookies like a browser
+// redirects are followed by tests, so that they can be tampered with
+func newBrowser(t *testing.T) *http.Client {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("failed to create cookie jar: %v", err)
+	}
+
+	return &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// sends request and returns its response with closed body
+func (s *service) do(t *testing.T, method, rawURL, token string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	return resp, body
+}
+
+// follows redirect of the response, location may be changed by tamper
+func (s *service) follow(t *testing.T, resp *http.Response, tamper func(u *url.URL)) (*http.Response, []byte) {
+	t.Helper()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect, got %d", resp.StatusCode)
+	}
+
+	u, err := resp.Location()
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	if tamper != nil {
+		tamper(u)
+	}
+
+	return s.do(t, http.MethodGet, u.String(), "")
+}
+
+// goes through provider from the start of login, returns response of provider's redirect to callback
+func (s *service) authorize(t *testing.T, provider string, tamper func(u *url.URL)) *http.Response {
+	t.Helper()
+
+	resp, _ := s.do(t, http.MethodGet, s.url+"/oidc/"+provider+"/login", "")
+	resp, _ = s.follow(t, resp, tamper)
+
+	return resp
+}
+
+// logs in with provider and returns response of callback
+func (s *service) login(t *testing.T, provider string) login.Response {
+	t.Helper()
+
+	resp, body := s.follow(t, s.authorize(t, provider, nil), nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login failed with %d: %s", resp.StatusCode, body)
+	}
+
+	var logged login.Response
+	if err := json.Unmarshal(body, &logged); err != nil {
+		t.Fatalf("invalid login response: %v", err)
+	}
+
+	return logged
+}
+
+// sets query parameter of url
+func setParam(name, value string) func(u *url.URL) {
+	return func(u *url.URL) {
+		q := u.Query()
+		q.Set(name, value)
+		u.RawQuery = q.Encode()
+	}
+}
+
+func TestLoginRegistersUserOnce(t *testing.T) {
+	s := newService(t)
+
+	s.provider.SetUser(oidctest.User{
+		Subject:           "42",
+		Email:             "John.Smith@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "john.smith",
+	})
+
+	first := s.login(t, "test")
+	if first.Login != "johnsmith" || first.Token == "" || first.RefreshToken == "" {
+		t.Fatalf("unexpected response of first login: %+v", first)
+	}
+
+	second := s.login(t, "test")
+	if second.Id != first.Id {
+		t.Fatalf("second login created another user: %d, want %d", second.Id, first.Id)
+	}
+}
+
+func TestCallbackRejectsState(t *testing.T) {
+	s := newService(t)
+
+	tests := []struct {
+		name   string
+		tamper func(u *url.URL)
+	}{
+		{"forged", setParam("state", "forged")},
+		{"missing", setParam("state", "")},
+		{"other provider", func(u *url.URL) { u.Path = "/oidc/other/callback" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := s.follow(t, s.authorize(t, "test", nil), tt.tamper)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", resp.StatusCode, body)
+			}
+		})
+	}
+}
+
+func TestCallbackRejectsStateOfAnotherBrowser(t *testing.T) {
+	s := newService(t)
+
+	// someone authorizes at the provider and sends the callback link to the victim
+	redirect := s.authorize(t, "test", nil)
+
+	victim := *s
+	victim.client = newBrowser(t)
+
+	resp, body := victim.follow(t, redirect, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestCallbackRejectsReusedState(t *testing.T) {
+	s := newService(t)
+
+	redirect := s.authorize(t, "test", nil)
+
+	resp, body := s.follow(t, redirect, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login failed with %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = s.follow(t, redirect, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 on reuse, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestCallbackRejectsTamperedAuthRequest(t *testing.T) {
+	s := newService(t)
+
+	tests := []struct {
+		name   string
+		tamper func(u *url.URL)
+	}{
+		// code is bound to another challenge, so the kept verifier doesn't match it
+		{"pkce challenge", setParam("code_challenge", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM")},
+		// id token carries a nonce the service didn't issue
+		{"nonce", setParam("nonce", "forged")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := s.follow(t, s.authorize(t, "test", tt.tamper), nil)
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d: %s", resp.StatusCode, body)
+			}
+		})
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	s := newService(t)
+
+	user := s.login(t, "test")
+
+	resp, body := s.do(t, http.MethodPost, s.url+"/me/identities/other", user.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("linking failed to start with %d: %s", resp.StatusCode, body)
+	}
+
+	var started link.Response
+	if err := json.Unmarshal(body, &started); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+
+	resp, _ = s.do(t, http.MethodGet, started.AuthURL, "")
+	resp, body = s.follow(t, resp, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("linking failed with %d: %s", resp.StatusCode, body)
+	}
+
+	var linked callback.LinkResponse
+	if err := json.Unmarshal(body, &linked); err != nil || linked.Provider != "other" {
+		t.Fatalf("unexpected response of linking: %s", body)
+	}
+
+	// the linked identity logs in to the same account
+	other := s.login(t, "other")
+	if other.Id != user.Id {
+		t.Fatalf("linked identity logged in as %d, want %d", other.Id, user.Id)
+	}
+
+	// the identity can't be linked twice
+	resp, body = s.do(t, http.MethodPost, s.url+"/me/identities/other", user.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("linking failed to start with %d: %s", resp.StatusCode, body)
+	}
+	if err := json.Unmarshal(body, &started); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+
+	resp, _ = s.do(t, http.MethodGet, started.AuthURL, "")
+	resp, body = s.follow(t, resp, nil)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 on second linking, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestLinkRejectsAnotherBrowser(t *testing.T) {
+	s := newService(t)
+
+	user := s.login(t, "test")
+
+	resp, body := s.do(t, http.MethodPost, s.url+"/me/identities/other", user.Token)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("linking failed to start with %d: %s", resp.StatusCode, body)
+	}
+
+	var started link.Response
+	if err := json.Unmarshal(body, &started); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+
+	// the victim would link their provider account to the account that started linking
+	victim := *s
+	victim.client = newBrowser(t)
+
+	resp, _ = victim.do(t, http.MethodGet, started.AuthURL, "")
+	resp, body = victim.follow(t, resp, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,81 @@
+package start
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/oidc"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type StateSaver interface {
+	SaveOIDCState(state *models.OIDCState) (*models.OIDCState, error)
+}
+
+// New creates a new HandlerFunc starting login with external provider
+// the user is redirected to the provider, which sends them back to callback
+// authorization request is valid for stateTL
+func New(log *slog.Logger, stateSaver StateSaver, providers oidc.Providers, stateTL time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.oidc.start.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		name := chi.URLParam(r, "provider")
+
+		provider, ok := providers[name]
+		if !ok {
+			log.Info("unknown provider", slog.String("provider", name))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("unknown provider"))
+
+			return
+		}
+
+		authReq, err := provider.NewAuthRequest(r.Context())
+		if err != nil {
+			log.Error("failed to start authorization", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadGateway)
+			render.JSON(w, r, response.Error("provider is unavailable"))
+
+			return
+		}
+
+		now := time.Now()
+
+		_, err = stateSaver.SaveOIDCState(&models.OIDCState{
+			StateHash: opaque.Hash(authReq.State),
+			Provider:  name,
+			Nonce:     authReq.Nonce,
+			Verifier:  authReq.Verifier,
+			CreatedAt: now,
+			ExpiresAt: now.Add(stateTL),
+		})
+		if err != nil {
+			log.Error("failed to save authorization request", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		oidc.SetStateCookie(w, r, name, authReq.State, stateTL)
+
+		log.Info("authorization started", slog.String("provider", name))
+
+		http.Redirect(w, r, authReq.URL, http.StatusFound)
+	}
+}
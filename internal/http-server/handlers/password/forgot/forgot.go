@@ -0,0 +1,161 @@
+package forgot
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/mailer"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type ResetSaver interface {
+	User(login string) (*models.User, error)
+	SavePasswordReset(pr *models.PasswordReset) (*models.PasswordReset, error)
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for requesting password reset token
+// the token is emailed to verified address of the user, requests per account are limited by limiter
+// the response is the same whether the account exists or not and whether it has verified email
+func New(
+	log *slog.Logger,
+	resetSaver ResetSaver,
+	mail mailer.Mailer,
+	limiter *bruteforce.Limiter,
+	resetTokenTL time.Duration,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.password.forgot.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req request.ForgotPasswordRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		req.Login = validate.NormalizeLogin(req.Login)
+
+		// not flooding user with notifications
+		wait, err := limiter.Check(req.Login, time.Now())
+		if err != nil {
+			log.Error("failed to check reset requests", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if wait > 0 {
+			log.Info("too many reset requests, ignoring", slog.String("user", req.Login))
+
+			render.JSON(w, r, response.OK())
+
+			return
+		}
+
+		user, err := resetSaver.User(req.Login)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("reset requested for unknown user", slog.String("user", req.Login))
+
+			render.JSON(w, r, response.OK())
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// token can't be sent anywhere else without letting it to someone who isn't the owner
+		if user.Email == "" || user.EmailVerifiedAt.IsZero() {
+			log.Info("reset requested for user without verified email", slog.String("user", user.Login))
+
+			render.JSON(w, r, response.OK())
+
+			return
+		}
+
+		if err := limiter.Fail(user.Login, time.Now()); err != nil {
+			log.Error("failed to record reset request", slog.String("error", err.Error()))
+		}
+
+		token, err := opaque.New()
+		if err != nil {
+			log.Error("failed to generate reset token", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// only hash of the token is stored
+		now := time.Now()
+		pr, err := resetSaver.SavePasswordReset(&models.PasswordReset{
+			UserId:    user.Id,
+			TokenHash: opaque.Hash(token),
+			CreatedAt: now,
+			ExpiresAt: now.Add(resetTokenTL),
+		})
+		if err != nil {
+			log.Error("failed to save reset token", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		err = mail.Send(user.Email, "Password reset", fmt.Sprintf(
+			"Use this token to reset your password on ad-market: %s\nIt expires at %s. If you didn't request it, ignore this message.",
+			token,
+			pr.ExpiresAt.Format(time.RFC3339),
+		))
+		// failure is answered as success, otherwise it would tell that the account has verified email
+		if err != nil {
+			log.Error("failed to send reset token", slog.String("error", err.Error()))
+
+			render.JSON(w, r, response.OK())
+
+			return
+		}
+
+		log.Info("password reset requested", slog.String("user", user.Login))
+
+		audit.Record(log, resetSaver, r, models.AuditEntry{
+			Event:  models.AuditPasswordResetRequest,
+			Target: "user:" + user.Login,
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,144 @@
+package reset
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/credentials"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type PasswordResetter interface {
+	PasswordReset(tokenHash string) (*models.PasswordReset, error)
+	UserByID(id int64) (*models.User, error)
+	ResetPassword(pr *models.PasswordReset, passHash []byte, at time.Time) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for setting new password with reset token
+// all sessions of the user are revoked and failed login attempts are forgotten
+func New(
+	log *slog.Logger,
+	passwordResetter PasswordResetter,
+	passwords *credentials.Manager,
+	policy *validate.PasswordPolicy,
+	guard *bruteforce.Guard,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.password.reset.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req request.ResetPasswordRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		now := time.Now()
+
+		pr, err := passwordResetter.PasswordReset(opaque.Hash(req.Token))
+		if err != nil && !errors.Is(err, storage.ErrTokenNotFound) {
+			log.Error("error finding reset token", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if err != nil || !pr.UsedAt.IsZero() || !now.Before(pr.ExpiresAt) {
+			log.Info("invalid reset token")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid or expired token"))
+
+			return
+		}
+
+		user, err := passwordResetter.UserByID(pr.UserId)
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		if validationErrs := policy.Validate(req.NewPassword, user.Login).OnField("new_password"); len(validationErrs) != 0 {
+			log.Info("invalid new password")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Invalid(validationErrs))
+
+			return
+		}
+
+		// hashing password
+		passHash, err := passwords.Hash(req.NewPassword)
+		if err != nil {
+			log.Error("failed to generate password hash", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		err = passwordResetter.ResetPassword(pr, passHash, now)
+		if errors.Is(err, storage.ErrTokenReused) {
+			log.Info("reset token used concurrently")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid or expired token"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to reset password", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// the owner has proved access to the account, so lockout is lifted
+		if err := guard.Unlock(user.Login); err != nil {
+			log.Error("failed to reset failed attempts", slog.String("error", err.Error()))
+		}
+
+		log.Info("password reset", slog.String("user", user.Login))
+
+		audit.Record(log, passwordResetter, r, models.AuditEntry{
+			Event:      models.AuditPasswordReset,
+			ActorId:    user.Id,
+			ActorLogin: user.Login,
+			Target:     "user:" + user.Login,
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
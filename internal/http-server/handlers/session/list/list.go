@@ -0,0 +1,77 @@
+package list
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type Session struct {
+	Id         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	Sessions []Session `json:"sessions"`
+}
+
+type SessionProvider interface {
+	UserSessions(userID int64) ([]models.Session, error)
+}
+
+// New creates a new HandlerFunc for listing active sessions of the authorized user
+func New(log *slog.Logger, sessionProv SessionProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.session.list.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+		currentID, _ := auth.SessionID(r.Context())
+
+		sessions, err := sessionProv.UserSessions(claims.ID)
+		if err != nil {
+			log.Error("failed to get sessions", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		resp := Response{
+			Response: response.OK(),
+			Sessions: []Session{},
+		}
+
+		for _, s := range sessions {
+			resp.Sessions = append(resp.Sessions, Session{
+				Id:         s.Id,
+				UserAgent:  s.UserAgent,
+				IP:         s.IP,
+				CreatedAt:  s.CreatedAt,
+				LastSeenAt: s.LastSeenAt,
+				Current:    s.Id == currentID,
+			})
+		}
+
+		log.Info("sessions accessed")
+
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,68 @@
+package revoke
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type SessionRevoker interface {
+	Session(id string) (*models.Session, error)
+	RevokeSession(id string) error
+}
+
+// New creates a new HandlerFunc for revoking a session of the authorized user
+func New(log *slog.Logger, sessionRevoker SessionRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.session.revoke.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+		id := chi.URLParam(r, "id")
+
+		// sessions of other users are reported as not found
+		session, err := sessionRevoker.Session(id)
+		if errors.Is(err, storage.ErrSessionNotFound) || (err == nil && session.UserId != claims.ID) {
+			log.Info("session not found", slog.String("session", id))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("session not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding session", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		if err := sessionRevoker.RevokeSession(session.Id); err != nil {
+			log.Error("failed to revoke session", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("session revoked")
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,43 @@
+package revokeall
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+)
+
+type SessionRevoker interface {
+	RevokeUserSessions(userID int64) error
+}
+
+// New creates a new HandlerFunc for logging the authorized user out everywhere
+func New(log *slog.Logger, sessionRevoker SessionRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.session.revokeall.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		if err := sessionRevoker.RevokeUserSessions(claims.ID); err != nil {
+			log.Error("failed to revoke sessions", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("all sessions revoked")
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,201 @@
+package refresh
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/jwt"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/lib/refresh"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type Response struct {
+	response.Response
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenRotator interface {
+	UserByID(id int64) (*models.User, error)
+	ActiveBan(userID int64) (*models.Ban, error)
+	RefreshToken(tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(usedID int64, next *models.RefreshToken) (*models.RefreshToken, error)
+	TouchSession(id string, at time.Time) error
+	RevokeSession(id string) error
+}
+
+// New creates a new HandlerFunc for exchanging refresh token for a new pair of tokens
+func New(log *slog.Logger, tokenRotator TokenRotator, tokens *jwt.Manager, tokenTL, refreshTL time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.token.refresh.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req request.RefreshRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		// getting refresh token from storage
+		rt, err := tokenRotator.RefreshToken(opaque.Hash(req.RefreshToken))
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Info("refresh token not found")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("invalid refresh token"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding refresh token", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// a token presented for the second time means it was stolen,
+		// so the whole session is revoked
+		if !rt.UsedAt.IsZero() || !rt.RevokedAt.IsZero() {
+			revokeSession(log, tokenRotator, rt.SessionId)
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("invalid refresh token"))
+
+			return
+		}
+
+		if time.Now().After(rt.ExpiresAt) {
+			log.Info("refresh token expired")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("refresh token expired"))
+
+			return
+		}
+
+		user, err := tokenRotator.UserByID(rt.UserId)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("owner of refresh token not found", slog.Int64("id", rt.UserId))
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("invalid refresh token"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.ErrorOf(err))
+
+			return
+		}
+
+		// banned users can't prolong their sessions, even if they weren't revoked
+		_, err = tokenRotator.ActiveBan(user.Id)
+		if err == nil {
+			log.Info("banned user tried to refresh tokens", slog.Int64("id", user.Id))
+
+			if err := tokenRotator.RevokeSession(rt.SessionId); err != nil {
+				log.Error("failed to revoke session", slog.String("error", err.Error()))
+			}
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("account is banned"))
+
+			return
+		}
+		if !errors.Is(err, storage.ErrBanNotFound) {
+			log.Error("error checking ban", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// rotating refresh token
+		refreshToken, next, err := refresh.New(user.Id, rt.SessionId, refreshTL)
+		if err != nil {
+			log.Error("failed to generate refresh token", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		_, err = tokenRotator.RotateRefreshToken(rt.Id, next)
+		if errors.Is(err, storage.ErrTokenReused) {
+			// token was used concurrently by someone else
+			revokeSession(log, tokenRotator, rt.SessionId)
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("invalid refresh token"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to rotate refresh token", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		if err := tokenRotator.TouchSession(rt.SessionId, next.CreatedAt); err != nil {
+			log.Error("failed to update session", slog.String("error", err.Error()))
+		}
+
+		// creating new jwt token
+		token, err := tokens.NewToken(*user, rt.SessionId, tokenTL)
+		if err != nil {
+			log.Error("failed to generate token", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("tokens refreshed", slog.Int64("id", user.Id))
+
+		render.JSON(w, r, Response{
+			Response:     response.OK(),
+			Token:        token,
+			RefreshToken: refreshToken,
+		})
+	}
+}
+
+// revokes session after refresh token reuse was detected
+func revokeSession(log *slog.Logger, tokenRotator TokenRotator, sessionID string) {
+	log.Warn("refresh token reuse detected, revoking session")
+
+	if err := tokenRotator.RevokeSession(sessionID); err != nil {
+		log.Error("failed to revoke session", slog.String("error", err.Error()))
+	}
+}
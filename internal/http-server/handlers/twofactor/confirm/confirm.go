@@ -0,0 +1,120 @@
+package confirm
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/totp"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type TOTPEnabler interface {
+	TOTP(userID int64) (*models.TOTP, error)
+	EnableTOTP(userID int64, step int64, at time.Time) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for enabling two-factor authentication of the authorized user
+// with the first code from authenticator app
+func New(log *slog.Logger, totpEnabler TOTPEnabler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.twofactor.confirm.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		var req request.TwoFactorCodeRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		t, err := totpEnabler.TOTP(claims.ID)
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			log.Info("two-factor authentication not set up")
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("two-factor authentication not set up"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get secret", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		if !t.EnabledAt.IsZero() {
+			log.Info("two-factor authentication already enabled")
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("two-factor authentication already enabled"))
+
+			return
+		}
+
+		step, ok := totp.Validate(t.Secret, req.Code, time.Now(), totp.Skew)
+		if !ok {
+			log.Info("invalid code")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("invalid code"))
+
+			return
+		}
+
+		// remembering step of the code, so that it can't be used again to log in
+		err = totpEnabler.EnableTOTP(claims.ID, step, time.Now())
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			log.Info("two-factor authentication already enabled")
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("two-factor authentication already enabled"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to enable two-factor authentication", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("two-factor authentication enabled", slog.Int64("user", claims.ID))
+
+		audit.Record(log, totpEnabler, r, models.AuditEntry{
+			Event:      models.AuditTwoFactorEnable,
+			ActorId:    claims.ID,
+			ActorLogin: claims.Login,
+			Target:     "user:" + claims.Login,
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,133 @@
+package disable
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/credentials"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type TOTPRemover interface {
+	UserByID(id int64) (*models.User, error)
+	DeleteTOTP(userID int64) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for disabling two-factor authentication of the authorized user
+// the password is required, wrong guesses are limited by guard as on login
+func New(
+	log *slog.Logger,
+	totpRemover TOTPRemover,
+	passwords *credentials.Manager,
+	guard *bruteforce.Guard,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.twofactor.disable.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		var req request.TwoFactorDisableRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		ip := request.ClientIP(r)
+
+		wait, err := guard.Check(claims.Login, ip, time.Now())
+		if err != nil {
+			log.Error("failed to check password attempts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if wait > 0 {
+			log.Info("too many failed attempts", slog.String("user", claims.Login), slog.Duration("wait", wait))
+
+			w.Header().Set("Retry-After", bruteforce.RetryAfter(wait))
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, response.Error("too many failed attempts, try again later"))
+
+			return
+		}
+
+		user, err := totpRemover.UserByID(claims.ID)
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// stolen access token alone is not enough to turn off the second factor
+		if ok, _ := passwords.Verify(user, req.Password); !ok {
+			log.Info("invalid password")
+
+			if err := guard.Fail(user.Login, ip, time.Now()); err != nil {
+				log.Error("failed to record failed attempt", slog.String("error", err.Error()))
+			}
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("invalid password"))
+
+			return
+		}
+
+		err = totpRemover.DeleteTOTP(user.Id)
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			log.Info("two-factor authentication not set up")
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("two-factor authentication not set up"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to disable two-factor authentication", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("two-factor authentication disabled", slog.String("user", user.Login))
+
+		audit.Record(log, totpRemover, r, models.AuditEntry{
+			Event:      models.AuditTwoFactorDisable,
+			ActorId:    user.Id,
+			ActorLogin: user.Login,
+			Target:     "user:" + user.Login,
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
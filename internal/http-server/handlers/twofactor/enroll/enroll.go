@@ -0,0 +1,97 @@
+package enroll
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/totp"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type Response struct {
+	response.Response
+	Secret        string   `json:"secret"`
+	URI           string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TOTPSaver interface {
+	SaveTOTP(t *models.TOTP, recoveryHashes []string) error
+}
+
+// New creates a new HandlerFunc for starting two-factor authentication setup of the authorized user
+// it's enabled after the user confirms it with a code from authenticator app
+// enrolling again before confirmation replaces the secret and recovery codes
+func New(log *slog.Logger, totpSaver TOTPSaver, issuer string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.twofactor.enroll.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, _ := auth.UserClaims(r.Context())
+
+		secret, err := totp.NewSecret()
+		if err != nil {
+			log.Error("failed to generate secret", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		codes, hashes, err := totp.NewRecoveryCodes()
+		if err != nil {
+			log.Error("failed to generate recovery codes", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		err = totpSaver.SaveTOTP(&models.TOTP{
+			UserId:    claims.ID,
+			Secret:    secret,
+			CreatedAt: time.Now(),
+		}, hashes)
+		if errors.Is(err, storage.ErrTOTPEnabled) {
+			log.Info("two-factor authentication already enabled")
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("two-factor authentication already enabled"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to save secret", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("two-factor authentication enrolled", slog.Int64("user", claims.ID))
+
+		render.JSON(w, r,
+			Response{
+				Response:      response.OK(),
+				Secret:        secret,
+				URI:           totp.URI(issuer, claims.Login, secret),
+				RecoveryCodes: codes,
+			},
+		)
+	}
+}
@@ -0,0 +1,238 @@
+package verify
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/handlers/user/login"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/jwt"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/session"
+	"github.com/rigbyel/ad-market/internal/lib/totp"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type UserProvider interface {
+	UserByID(id int64) (*models.User, error)
+	MFAChallenge(tokenHash string) (*models.MFAChallenge, error)
+	FailMFAChallenge(id int64) error
+	UseMFAChallengeWithTOTP(challengeID, userID int64, step int64, at time.Time) error
+	UseMFAChallengeWithRecoveryCode(challengeID, userID int64, codeHash string, at time.Time) error
+	TOTP(userID int64) (*models.TOTP, error)
+	ActiveBan(userID int64) (*models.Ban, error)
+	CreateSession(session *models.Session, rt *models.RefreshToken) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for completing login with the second factor
+// token issued by /login is exchanged for access and refresh tokens if the code is valid
+// each code is accepted once, wrong codes are limited per token and by guard as wrong passwords
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	tokens *jwt.Manager,
+	guard *bruteforce.Guard,
+	tokenTL, refreshTL time.Duration,
+	maxAttempts int,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.twofactor.verify.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req request.TwoFactorLoginRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		challenge, err := userProvider.MFAChallenge(opaque.Hash(req.MFAToken))
+		if err != nil && !errors.Is(err, storage.ErrTokenNotFound) {
+			log.Error("failed to get mfa token", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if challenge == nil || !challenge.UsedAt.IsZero() || time.Now().After(challenge.ExpiresAt) ||
+			challenge.Attempts >= maxAttempts {
+			log.Info("invalid mfa token")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("invalid or expired mfa token"))
+
+			return
+		}
+
+		user, err := userProvider.UserByID(challenge.UserId)
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// user may have been banned after entering the password
+		if login.RejectBanned(log, w, r, userProvider, user) {
+			return
+		}
+
+		ip := request.ClientIP(r)
+
+		wait, err := guard.Check(user.Login, ip, time.Now())
+		if err != nil {
+			log.Error("failed to check login attempts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if wait > 0 {
+			log.Info("too many failed attempts", slog.String("user", user.Login), slog.Duration("wait", wait))
+
+			w.Header().Set("Retry-After", bruteforce.RetryAfter(wait))
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, response.Error("too many failed attempts, try again later"))
+
+			return
+		}
+
+		// token is used together with the code, so it can't be exchanged twice
+		// even if concurrent requests passed the checks above
+		method, details, err := checkCode(userProvider, challenge.Id, user.Id, req)
+		if errors.Is(err, storage.ErrTokenReused) {
+			log.Info("mfa token already used")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("invalid or expired mfa token"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to check code", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if details != "" {
+			log.Info("invalid code", slog.String("user", user.Login), slog.String("details", details))
+
+			audit.Record(log, userProvider, r, models.AuditEntry{
+				Event:   models.AuditLoginFailure,
+				Target:  "user:" + user.Login,
+				Details: details,
+			})
+
+			if err := userProvider.FailMFAChallenge(challenge.Id); err != nil {
+				log.Error("failed to record failed attempt", slog.String("error", err.Error()))
+			}
+			if err := guard.Fail(user.Login, ip, time.Now()); err != nil {
+				log.Error("failed to record failed attempt", slog.String("error", err.Error()))
+			}
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("invalid code"))
+
+			return
+		}
+
+		log.Info("user logged in with second factor", slog.String("user", user.Login))
+
+		// starting new session with its access and refresh tokens
+		issued, err := session.Start(userProvider, *user, r, tokens, tokenTL, refreshTL)
+		if err != nil {
+			log.Error("failed to start session", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		audit.Record(log, userProvider, r, models.AuditEntry{
+			Event:      models.AuditLoginSuccess,
+			ActorId:    user.Id,
+			ActorLogin: user.Login,
+			Target:     "user:" + user.Login,
+			Details:    "session=" + issued.SessionID + " 2fa=" + method,
+		})
+
+		render.JSON(w, r,
+			login.Response{
+				Response:     response.OK(),
+				Login:        user.Login,
+				Id:           user.Id,
+				Token:        issued.AccessToken,
+				RefreshToken: issued.RefreshToken,
+			},
+		)
+	}
+}
+
+// checks authenticator or recovery code and marks it used along with the mfa token
+// returns the method used and, if the code is rejected, the reason
+// storage.ErrTokenReused is returned if the token has already been used
+func checkCode(userProvider UserProvider, challengeID, userID int64, req request.TwoFactorLoginRequest) (method, details string, err error) {
+	if req.RecoveryCode != "" {
+		err := userProvider.UseMFAChallengeWithRecoveryCode(challengeID, userID, totp.HashRecoveryCode(req.RecoveryCode), time.Now())
+		if errors.Is(err, storage.ErrRecoveryCodeNotFound) {
+			return "recovery", "wrong recovery code", nil
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		return "recovery", "", nil
+	}
+
+	// two-factor authentication may have been disabled after the password was entered
+	t, err := userProvider.TOTP(userID)
+	if errors.Is(err, storage.ErrTOTPNotFound) {
+		return "totp", "2fa disabled", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	step, ok := totp.Validate(t.Secret, req.Code, time.Now(), totp.Skew)
+	if !ok {
+		return "totp", "wrong 2fa code", nil
+	}
+
+	// code of the same or earlier step has already been accepted, it may be intercepted
+	err = userProvider.UseMFAChallengeWithTOTP(challengeID, userID, step, time.Now())
+	if errors.Is(err, storage.ErrCodeReused) {
+		return "totp", "reused 2fa code", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return "totp", "", nil
+}
@@ -0,0 +1,93 @@
+package adverts
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/feed"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type Response struct {
+	response.Response
+	Adverts *[]feed.Advert `json:"adverts"`
+}
+
+type AdProvider interface {
+	User(login string) (*models.User, error)
+	AdvertsByAuthor(login string) (*[]models.Advert, error)
+}
+
+// New creates a new HandlerFunc for showing adverts of a single seller
+func New(log *slog.Logger, adProv AdProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.adverts.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// check if request was authorized
+		login, isAuthorized := auth.UserLogin(r.Context())
+		if isAuthorized {
+			log.Info("user authorized")
+		}
+
+		// check if seller exists
+		seller, err := adProv.User(chi.URLParam(r, "login"))
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("user", chi.URLParam(r, "login")))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("user not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// get all adverts of the seller from storage
+		adverts, err := adProv.AdvertsByAuthor(seller.Login)
+		if err != nil {
+			log.Error("failed to get adverts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// preparing adverts to show according to filters from query
+		pageAdverts, err := feed.PrepareAdverts(r, adverts, isAuthorized, login)
+		if err != nil {
+			log.Info("failed to get page of adverts", slog.String("error", err.Error()))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.ErrorOf(err))
+
+			return
+		}
+
+		log.Info("seller adverts accessed", slog.String("user", seller.Login))
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Adverts:  &pageAdverts,
+		})
+	}
+}
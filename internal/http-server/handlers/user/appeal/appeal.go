@@ -0,0 +1,167 @@
+package appeal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/credentials"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type AppealSaver interface {
+	User(login string) (*models.User, error)
+	ActiveBan(userID int64) (*models.Ban, error)
+	SetBanAppeal(id int64, appeal string, at time.Time) error
+	audit.Saver
+}
+
+// New creates a new HandlerFunc for appealing against a ban
+// banned users can't authorize, so they confirm their identity with login and password
+// failed attempts share limits with login
+func New(
+	log *slog.Logger,
+	appealSaver AppealSaver,
+	passwords *credentials.Manager,
+	guard *bruteforce.Guard,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.appeal.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req request.AppealRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		req.Login = validate.NormalizeLogin(req.Login)
+		req.Appeal = validate.NormalizeText(req.Appeal)
+
+		if req.Appeal == "" || validate.TextLength(req.Appeal) > constraints.AppealMaxLen {
+			log.Info("invalid appeal")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("appeal is required and should be shorter than 1000 characters"))
+
+			return
+		}
+
+		// limiting failed attempts per account and per ip address
+		ip := request.ClientIP(r)
+
+		wait, err := guard.Check(req.Login, ip, time.Now())
+		if err != nil {
+			log.Error("failed to check login attempts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if wait > 0 {
+			log.Info("too many failed attempts", slog.String("user", req.Login), slog.Duration("wait", wait))
+
+			w.Header().Set("Retry-After", bruteforce.RetryAfter(wait))
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, response.Error("too many failed attempts, try again later"))
+
+			return
+		}
+
+		// checking user's credentials
+		user, err := appealSaver.User(req.Login)
+		if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if ok, _ := passwords.Verify(user, req.Password); !ok {
+			log.Info("invalid credentials", slog.String("user", req.Login))
+
+			if err := guard.Fail(req.Login, ip, time.Now()); err != nil {
+				log.Error("failed to record failed attempt", slog.String("error", err.Error()))
+			}
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("invalid credentials"))
+
+			return
+		}
+
+		ban, err := appealSaver.ActiveBan(user.Id)
+		if errors.Is(err, storage.ErrBanNotFound) {
+			log.Info("user is not banned", slog.String("user", user.Login))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("account is not banned"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding ban", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// one appeal per ban
+		if !ban.AppealedAt.IsZero() {
+			log.Info("ban already appealed", slog.Int64("ban", ban.Id))
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("appeal already submitted"))
+
+			return
+		}
+
+		if err := appealSaver.SetBanAppeal(ban.Id, req.Appeal, time.Now()); err != nil {
+			log.Error("failed to save appeal", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("ban appealed", slog.Int64("ban", ban.Id))
+
+		audit.Record(log, appealSaver, r, models.AuditEntry{
+			Event:      models.AuditUserAppeal,
+			ActorId:    user.Id,
+			ActorLogin: user.Login,
+			Target:     "user:" + user.Login,
+			Details:    fmt.Sprintf("ban=%d", ban.Id),
+		})
+
+		render.JSON(w, r, response.OK())
+	}
+}
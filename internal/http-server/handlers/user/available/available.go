@@ -0,0 +1,100 @@
+package available
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type Response struct {
+	response.Response
+	Available bool `json:"available"`
+}
+
+type UserProvider interface {
+	UserByLoginKey(key string) (*models.User, error)
+}
+
+// New creates a new HandlerFunc for checking if login is free before registration
+// taken logins found are limited per ip address by limiter shared with registration
+// logins differing only in case or look-alike letters are treated as taken
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	loginPolicy *validate.LoginPolicy,
+	limiter *bruteforce.Limiter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.available.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		login := validate.NormalizeLogin(r.URL.Query().Get("login"))
+
+		if validationErrs := loginPolicy.Validate(login); len(validationErrs) != 0 {
+			log.Info("invalid login")
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Invalid(validationErrs))
+
+			return
+		}
+
+		ip := request.ClientIP(r)
+
+		wait, err := limiter.Check(ip, time.Now())
+		if err != nil {
+			log.Error("failed to check attempts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if wait > 0 {
+			log.Info("too many taken logins checked", slog.String("ip", ip))
+
+			w.Header().Set("Retry-After", bruteforce.RetryAfter(wait))
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, response.Error("too many attempts, try again later"))
+
+			return
+		}
+
+		_, err = userProvider.UserByLoginKey(validate.LoginKey(login))
+		if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		available := errors.Is(err, storage.ErrUserNotFound)
+		if !available {
+			if err := limiter.Fail(ip, time.Now()); err != nil {
+				log.Error("failed to record attempt", slog.String("error", err.Error()))
+			}
+		}
+
+		render.JSON(w, r, Response{
+			Response:  response.OK(),
+			Available: available,
+		})
+	}
+}
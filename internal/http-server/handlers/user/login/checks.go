@@ -0,0 +1,134 @@
+package login
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+// checks below are shared by all ways to log in: password, provider and second factor
+
+type BanChecker interface {
+	ActiveBan(userID int64) (*models.Ban, error)
+	audit.Saver
+}
+
+type ChallengeSaver interface {
+	TOTP(userID int64) (*models.TOTP, error)
+	SaveMFAChallenge(c *models.MFAChallenge) (*models.MFAChallenge, error)
+}
+
+// RejectBanned responds with 403 and BanResponse if the user is banned
+// returns true if the response is written and login must stop
+func RejectBanned(log *slog.Logger, w http.ResponseWriter, r *http.Request, banChecker BanChecker, user *models.User) bool {
+	ban, err := banChecker.ActiveBan(user.Id)
+	if errors.Is(err, storage.ErrBanNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Error("error checking ban", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("internal error"))
+
+		return true
+	}
+
+	log.Info("banned user tried to log in", slog.String("user", user.Login))
+
+	audit.Record(log, banChecker, r, models.AuditEntry{
+		Event:   models.AuditLoginFailure,
+		Target:  "user:" + user.Login,
+		Details: "banned",
+	})
+
+	resp := BanResponse{
+		Response: response.Error("account is banned"),
+		Reason:   ban.Reason,
+	}
+	if !ban.ExpiresAt.IsZero() {
+		resp.ExpiresAt = &ban.ExpiresAt
+	}
+
+	render.Status(r, http.StatusForbidden)
+	render.JSON(w, r, resp)
+
+	return true
+}
+
+// ChallengeSecondFactor responds with MFAResponse carrying token valid for mfaTL
+// if the user has two-factor authentication enabled
+// returns true if the response is written and login must stop
+func ChallengeSecondFactor(
+	log *slog.Logger,
+	w http.ResponseWriter,
+	r *http.Request,
+	challengeSaver ChallengeSaver,
+	user *models.User,
+	mfaTL time.Duration,
+) bool {
+	t, err := challengeSaver.TOTP(user.Id)
+	if errors.Is(err, storage.ErrTOTPNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Error("failed to get two-factor settings", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("internal error"))
+
+		return true
+	}
+
+	// enrollment isn't confirmed yet
+	if t.EnabledAt.IsZero() {
+		return false
+	}
+
+	mfaToken, err := opaque.New()
+	if err != nil {
+		log.Error("failed to generate mfa token", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("internal error"))
+
+		return true
+	}
+
+	now := time.Now()
+
+	_, err = challengeSaver.SaveMFAChallenge(&models.MFAChallenge{
+		UserId:    user.Id,
+		TokenHash: opaque.Hash(mfaToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(mfaTL),
+	})
+	if err != nil {
+		log.Error("failed to save mfa token", slog.String("error", err.Error()))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("internal error"))
+
+		return true
+	}
+
+	log.Info("second factor required", slog.String("user", user.Login))
+
+	render.JSON(w, r,
+		MFAResponse{
+			Response:    response.OK(),
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		},
+	)
+
+	return true
+}
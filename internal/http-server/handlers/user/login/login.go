@@ -8,105 +8,220 @@ import (
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/credentials"
 	"github.com/rigbyel/ad-market/internal/lib/jwt"
 	"github.com/rigbyel/ad-market/internal/lib/request"
 	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/lib/session"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
 	"github.com/rigbyel/ad-market/internal/models"
 	"github.com/rigbyel/ad-market/internal/storage"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Response struct {
 	response.Response
-	Login string `json:"login"`
-	Id    int64  `json:"id"`
-	Token string `json:"token"`
+	Login        string `json:"login"`
+	Id           int64  `json:"id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// BanResponse tells banned user why and until when they can't log in
+type BanResponse struct {
+	response.Response
+	Reason string `json:"ban_reason"`
+
+	// omitted for permanent bans
+	ExpiresAt *time.Time `json:"ban_expires_at,omitempty"`
+}
+
+// MFAResponse asks the user for the second factor
+// login is completed by sending the code along with MFAToken to /login/2fa
+type MFAResponse struct {
+	response.Response
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
 }
 
 type UserProvider interface {
 	User(login string) (*models.User, error)
+	CreateSession(session *models.Session, rt *models.RefreshToken) error
+	ActiveBan(userID int64) (*models.Ban, error)
+	TOTP(userID int64) (*models.TOTP, error)
+	SaveMFAChallenge(c *models.MFAChallenge) (*models.MFAChallenge, error)
+	SetPassword(userID int64, passHash []byte) error
+	audit.Saver
 }
 
 // New create a HandlerFunc to handle /login endpoint
-func New(log *slog.Logger, userProvider UserProvider, authSecret string, tokenTL time.Duration) http.HandlerFunc {
+// failed attempts are limited by guard
+// outdated password hashes are replaced with ones made by current hasher on successful login
+// users with two-factor authentication get token valid for mfaTL to enter the code with instead of session
+func New(
+	log *slog.Logger,
+	userProvider UserProvider,
+	passwords *credentials.Manager,
+	tokens *jwt.Manager,
+	guard *bruteforce.Guard,
+	tokenTL, refreshTL, mfaTL time.Duration,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.user.Login.New"
 
 		// setting up logger
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// checking if user already authorized
-		tokenString := r.Header.Get("Authorization-access")
-		_, err := jwt.GetTokenClaims(tokenString, authSecret)
-		if err == nil {
-			log.Info("user already authorized")
-
-			render.JSON(w, r, response.Error("user already authorized"))
-
-			return
-		}
-
 		var req request.UserRequest
 
 		// decoding request
-		err = render.DecodeJSON(r.Body, &req)
+		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
 			log.Error("failed to decode request body", slog.String("error", err.Error()))
 
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error("failed to decode request body"))
 			return
 		}
 
 		log.Info("request body decoded", slog.Any("request", req))
 
-		// getting user info from storage
-		user, err := userProvider.User(req.Login)
-		if errors.Is(err, storage.ErrUserNotFound) {
-			log.Info("user not found", slog.String("user", req.Login))
+		req.Login = validate.NormalizeLogin(req.Login)
+
+		// limiting failed attempts per account and per ip address
+		ip := request.ClientIP(r)
+
+		wait, err := guard.Check(req.Login, ip, time.Now())
+		if err != nil {
+			log.Error("failed to check login attempts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if wait > 0 {
+			log.Info("too many failed attempts", slog.String("user", req.Login), slog.Duration("wait", wait))
 
-			render.JSON(w, r, response.Error("user not found"))
+			w.Header().Set("Retry-After", bruteforce.RetryAfter(wait))
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, response.Error("too many failed attempts, try again later"))
 
 			return
 		}
-		if err != nil {
+
+		// getting user info from storage
+		user, err := userProvider.User(req.Login)
+		if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
 			log.Error("error finding user", slog.String("error", err.Error()))
 
-			render.JSON(w, r, response.Error("error finding user"))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
 
 			return
 		}
 
 		// checking user's password
-		if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(req.Password)); err != nil {
-			log.Info("invalid credentials", slog.String("error", err.Error()))
-
+		// unknown login and wrong password get the same response, so that existing accounts can't be found out
+		ok, rehash := passwords.Verify(user, req.Password)
+		if !ok {
+			details := "wrong password"
+			if user == nil {
+				details = "unknown user"
+			}
+
+			log.Info("invalid credentials", slog.String("user", req.Login), slog.String("details", details))
+
+			audit.Record(log, userProvider, r, models.AuditEntry{
+				Event:   models.AuditLoginFailure,
+				Target:  "user:" + req.Login,
+				Details: details,
+			})
+
+			if err := guard.Fail(req.Login, ip, time.Now()); err != nil {
+				log.Error("failed to record failed attempt", slog.String("error", err.Error()))
+			}
+
+			render.Status(r, http.StatusUnauthorized)
 			render.JSON(w, r, response.Error("invalid credentials"))
 
 			return
 		}
 
+		// password is correct, so previous failures are forgiven
+		if err := guard.Unlock(user.Login); err != nil {
+			log.Error("failed to reset failed attempts", slog.String("error", err.Error()))
+		}
+
+		// password is known only now, so it's the moment to upgrade its hash
+		if rehash {
+			upgradeHash(log, userProvider, passwords, user, req.Password)
+		}
+
+		// banned users can't log in
+		if RejectBanned(log, w, r, userProvider, user) {
+			return
+		}
+
+		// second factor is required if the user enabled it
+		if ChallengeSecondFactor(log, w, r, userProvider, user, mfaTL) {
+			return
+		}
+
 		log.Info("user logged in successfully")
 
-		// creating new jwt token
-		token, err := jwt.NewToken(*user, authSecret, tokenTL)
+		// starting new session with its access and refresh tokens
+		issued, err := session.Start(userProvider, *user, r, tokens, tokenTL, refreshTL)
 		if err != nil {
-			log.Error("failed to generate token", slog.String("string", err.Error()))
+			log.Error("failed to start session", slog.String("error", err.Error()))
 
+			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, response.Error("internal error"))
 
 			return
 		}
 
+		audit.Record(log, userProvider, r, models.AuditEntry{
+			Event:      models.AuditLoginSuccess,
+			ActorId:    user.Id,
+			ActorLogin: user.Login,
+			Target:     "user:" + user.Login,
+			Details:    "session=" + issued.SessionID,
+		})
+
 		render.JSON(w, r,
 			Response{
-				Response: response.OK(),
-				Login:    user.Login,
-				Id:       user.Id,
-				Token:    token,
+				Response:     response.OK(),
+				Login:        user.Login,
+				Id:           user.Id,
+				Token:        issued.AccessToken,
+				RefreshToken: issued.RefreshToken,
 			},
 		)
 	}
 }
+
+// replaces outdated hash of the user's password
+// login doesn't fail if it can't be done, the old hash still works
+func upgradeHash(log *slog.Logger, userProvider UserProvider, passwords *credentials.Manager, user *models.User, password string) {
+	passHash, err := passwords.Hash(password)
+	if err != nil {
+		log.Error("failed to generate password hash", slog.String("error", err.Error()))
+
+		return
+	}
+
+	if err := userProvider.SetPassword(user.Id, passHash); err != nil {
+		log.Error("failed to upgrade password hash", slog.String("error", err.Error()))
+
+		return
+	}
+
+	user.PassHash = passHash
+
+	log.Info("password hash upgraded", slog.String("user", user.Login))
+}
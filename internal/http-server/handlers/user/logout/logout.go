@@ -0,0 +1,78 @@
+package logout
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type TokenRevoker interface {
+	RefreshToken(tokenHash string) (*models.RefreshToken, error)
+	RevokeSession(id string) error
+}
+
+// New creates a new HandlerFunc for revoking refresh token on logout
+func New(log *slog.Logger, tokenRevoker TokenRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.logout.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req request.RefreshRequest
+
+		// decoding request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to decode request body"))
+
+			return
+		}
+
+		rt, err := tokenRevoker.RefreshToken(opaque.Hash(req.RefreshToken))
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			// nothing to revoke, user is logged out anyway
+			log.Info("refresh token not found")
+
+			render.JSON(w, r, response.OK())
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding refresh token", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// revoking the session the token belongs to
+		if err := tokenRevoker.RevokeSession(rt.SessionId); err != nil {
+			log.Error("failed to revoke session", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("user logged out", slog.Int64("id", rt.UserId))
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,92 @@
+package profile
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+type Response struct {
+	response.Response
+	Login         string     `json:"login"`
+	RegDate       *time.Time `json:"reg_date,omitempty"`
+	Rating        float64    `json:"rating"`
+	ActiveAdverts int        `json:"active_adverts"`
+	Bio           string     `json:"bio,omitempty"`
+	AvatarURL     string     `json:"avatar_url,omitempty"`
+}
+
+type ProfileProvider interface {
+	User(login string) (*models.User, error)
+	ActiveAdvertsCount(login string) (int, error)
+}
+
+// New creates a new HandlerFunc for showing public profile of a seller
+func New(log *slog.Logger, profileProv ProfileProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.profile.New"
+
+		// setting up logger
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		login := chi.URLParam(r, "login")
+
+		// getting user info from storage
+		user, err := profileProv.User(login)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found", slog.String("user", login))
+
+			render.Status(r, response.StatusOf(err))
+			render.JSON(w, r, response.Error("user not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("error finding user", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		// counting seller's active adverts
+		activeAdverts, err := profileProv.ActiveAdvertsCount(user.Login)
+		if err != nil {
+			log.Error("failed to count adverts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+
+		log.Info("profile accessed", slog.String("user", user.Login))
+
+		resp := Response{
+			Response:      response.OK(),
+			Login:         user.Login,
+			Rating:        user.Rating,
+			ActiveAdverts: activeAdverts,
+			Bio:           user.Bio,
+			AvatarURL:     user.AvatarURL,
+		}
+
+		if !user.RegDate.IsZero() {
+			resp.RegDate = &user.RegDate
+		}
+
+		render.JSON(w, r, resp)
+	}
+}
@@ -4,17 +4,19 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
-	"github.com/rigbyel/ad-market/internal/lib/jwt"
+	"github.com/rigbyel/ad-market/internal/lib/audit"
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+	"github.com/rigbyel/ad-market/internal/lib/credentials"
+	"github.com/rigbyel/ad-market/internal/lib/emailverify"
 	"github.com/rigbyel/ad-market/internal/lib/request"
 	"github.com/rigbyel/ad-market/internal/lib/response"
 	"github.com/rigbyel/ad-market/internal/lib/validate"
 	"github.com/rigbyel/ad-market/internal/models"
 	"github.com/rigbyel/ad-market/internal/storage"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Response struct {
@@ -24,62 +26,95 @@ type Response struct {
 
 type UserSaver interface {
 	SaveUser(u *models.User) (*models.User, error)
+	audit.Saver
 }
 
 // New creates a new HandlerFunc to handle user registration
-func New(log *slog.Logger, userSaver UserSaver, authSecret string) http.HandlerFunc {
+// attempts to register taken logins are limited per ip address by limiter
+// verification link is sent by verifier if email is given
+func New(
+	log *slog.Logger,
+	userSaver UserSaver,
+	passwords *credentials.Manager,
+	policy *validate.PasswordPolicy,
+	loginPolicy *validate.LoginPolicy,
+	limiter *bruteforce.Limiter,
+	verifier *emailverify.Verifier,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.user.register.New"
 
 		// setting up logger
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		// checking if user already authorized
-		tokenString := r.Header.Get("Authorization-access")
-		_, err := jwt.GetTokenClaims(tokenString, authSecret)
-		if err == nil {
-			log.Info("user already authorized")
-
-			render.JSON(w, r, response.Error("user already authorized"))
-
-			return
-		}
-
 		var req request.UserRequest
 
 		// decoding request
-		err = render.DecodeJSON(r.Body, &req)
+		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
 			log.Error("failed to decode request body", slog.String("error", err.Error()))
 
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error("failed to decode request body"))
 			return
 		}
 
 		log.Info("request body decoded", slog.Any("request", req))
 
+		// too many attempts to register taken logins look like probing for existing accounts
+		ip := request.ClientIP(r)
+
+		wait, err := limiter.Check(ip, time.Now())
+		if err != nil {
+			log.Error("failed to check registration attempts", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if wait > 0 {
+			log.Info("too many attempts to register taken logins", slog.String("ip", ip))
+
+			w.Header().Set("Retry-After", bruteforce.RetryAfter(wait))
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, response.Error("too many attempts, try again later"))
+
+			return
+		}
+
 		// validating login and password
-		var validationErrs []string
+		var validationErrs validate.Errors
+
+		req.Login = validate.NormalizeLogin(req.Login)
 
-		validationErrs = validate.ValidatePassword(req.Password)
-		validationErrs = append(validationErrs, validate.ValidateLogin(req.Login)...)
+		validationErrs = policy.Validate(req.Password, req.Login)
+		validationErrs = append(validationErrs, loginPolicy.Validate(req.Login)...)
+
+		// email is optional
+		req.Email = validate.NormalizeEmail(req.Email)
+		if req.Email != "" {
+			validationErrs = append(validationErrs, validate.ValidateEmail(req.Email)...)
+		}
 
 		if len(validationErrs) != 0 {
 			log.Error("invalid request data")
 
-			render.JSON(w, r, response.Error(strings.Join(validationErrs, ", ")))
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Invalid(validationErrs))
 
 			return
 		}
 
 		// hashing password
-		passHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		passHash, err := passwords.Hash(req.Password)
 		if err != nil {
 			log.Error("failed to generate password hash", slog.String("error", err.Error()))
 
+			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, response.Error("internal error"))
 
 			return
@@ -88,7 +123,10 @@ func New(log *slog.Logger, userSaver UserSaver, authSecret string) http.HandlerF
 		// creating user
 		user := &models.User{
 			Login:    req.Login,
+			LoginKey: validate.LoginKey(req.Login),
 			PassHash: passHash,
+			RegDate:  time.Now(),
+			Email:    req.Email,
 		}
 
 		// saving user in the storage
@@ -96,6 +134,11 @@ func New(log *slog.Logger, userSaver UserSaver, authSecret string) http.HandlerF
 		if errors.Is(err, storage.ErrUserExists) {
 			log.Info("user already exists", slog.String("user", req.Login))
 
+			if err := limiter.Fail(ip, time.Now()); err != nil {
+				log.Error("failed to record attempt", slog.String("error", err.Error()))
+			}
+
+			render.Status(r, response.StatusOf(err))
 			render.JSON(w, r, response.Error("user already exists"))
 
 			return
@@ -103,6 +146,7 @@ func New(log *slog.Logger, userSaver UserSaver, authSecret string) http.HandlerF
 		if err != nil {
 			log.Error("error creating user", slog.String("error", err.Error()))
 
+			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, response.Error("error creating user"))
 
 			return
@@ -110,6 +154,21 @@ func New(log *slog.Logger, userSaver UserSaver, authSecret string) http.HandlerF
 
 		log.Info("user added", slog.Int64("id", user.Id))
 
+		audit.Record(log, userSaver, r, models.AuditEntry{
+			Event:      models.AuditRegister,
+			ActorId:    user.Id,
+			ActorLogin: user.Login,
+			Target:     "user:" + user.Login,
+		})
+
+		// registration succeeds even if the link can't be sent, it can be requested again
+		if user.Email != "" {
+			if err := verifier.Send(user.Id, user.Email); err != nil {
+				log.Error("failed to send verification link", slog.String("error", err.Error()))
+			}
+		}
+
+		render.Status(r, http.StatusCreated)
 		render.JSON(w, r,
 			Response{
 				Response: response.OK(),
@@ -0,0 +1,510 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/lib/apikey"
+	"github.com/rigbyel/ad-market/internal/lib/jwt"
+	"github.com/rigbyel/ad-market/internal/lib/rbac"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/storage"
+)
+
+const (
+	realm = "ad-market"
+
+	// non-standard header supported for existing clients
+	legacyHeader = "Authorization-access"
+)
+
+var (
+	errNoToken        = errors.New("no access token")
+	errMalformedToken = errors.New("authorization header should have Bearer scheme")
+	errRevokedSession = errors.New("session was revoked")
+	errBanned         = errors.New("user is banned")
+
+	// credentials couldn't be checked, e.g. storage is down, it's not a fault of the client
+	errUnavailable = errors.New("authorization is unavailable")
+
+	errKeyNotAccepted    = errors.New("api keys are not accepted for this endpoint")
+	errInvalidKey        = errors.New("invalid api key")
+	errRevokedKey        = errors.New("api key was revoked")
+	errExpiredKey        = errors.New("api key expired")
+	errInsufficientScope = errors.New("api key doesn't have required scope")
+)
+
+type ctxKey struct{}
+
+type apiKeyCtxKey struct{}
+
+type SessionProvider interface {
+	Session(id string) (*models.Session, error)
+	TouchSession(id string, at time.Time) error
+}
+
+type BanProvider interface {
+	ActiveBan(userID int64) (*models.Ban, error)
+}
+
+type APIKeyProvider interface {
+	APIKey(prefix string) (*models.APIKey, error)
+	TouchAPIKey(id int64, at time.Time) error
+}
+
+type UserProvider interface {
+	UserByID(id int64) (*models.User, error)
+}
+
+type Storage interface {
+	SessionProvider
+	BanProvider
+	APIKeyProvider
+	UserProvider
+}
+
+// Auth authorizes requests via jwt token and stores user claims in request context
+type Auth struct {
+	log          *slog.Logger
+	tokens       *jwt.Manager
+	legacyHeader bool
+	storage      Storage
+}
+
+// New creates a new Auth middleware set
+// legacyHeader enables reading token from Authorization-access header
+func New(log *slog.Logger, tokens *jwt.Manager, legacyHeader bool, storage Storage) *Auth {
+	return &Auth{
+		log:          log,
+		tokens:       tokens,
+		legacyHeader: legacyHeader,
+		storage:      storage,
+	}
+}
+
+// RequireAuth rejects requests without a valid access token with 401
+// API keys are not accepted
+func (a *Auth) RequireAuth(next http.Handler) http.Handler {
+	return a.require(next, "middleware.auth.RequireAuth", "")
+}
+
+// RequireScope creates middleware rejecting requests without a valid access token
+// or API key having the scope
+func (a *Auth) RequireScope(scope rbac.Scope) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return a.require(next, "middleware.auth.RequireScope", scope)
+	}
+}
+
+// rejects requests without valid credentials, API keys are accepted if scope is given
+func (a *Auth) require(next http.Handler, op string, scope rbac.Scope) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := a.logger(r, op)
+
+		tokenString, err := a.token(r)
+		if errors.Is(err, errNoToken) {
+			log.Info("no access token")
+
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", realm))
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("authorization required"))
+
+			return
+		}
+		if err != nil {
+			log.Info("invalid authorization header", slog.String("error", err.Error()))
+
+			challenge(w, "invalid_request", err.Error())
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("authorization failed"))
+
+			return
+		}
+
+		ctx, err := a.authenticate(r.Context(), tokenString, scope)
+		if errors.Is(err, errUnavailable) {
+			log.Error("failed to authorize", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if errors.Is(err, errKeyNotAccepted) || errors.Is(err, errInsufficientScope) {
+			log.Info("api key not accepted", slog.String("error", err.Error()))
+
+			challenge(w, "insufficient_scope", describe(err))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("authorization failed: "+describe(err)))
+
+			return
+		}
+		if err != nil {
+			log.Info("authorization failed", slog.String("error", err.Error()))
+
+			challenge(w, "invalid_token", describe(err))
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("authorization failed: "+describe(err)))
+
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// OptionalAuth authorizes request if it has a valid access token
+// and lets it through as anonymous otherwise
+func (a *Auth) OptionalAuth(next http.Handler) http.Handler {
+	return a.optional(next, "middleware.auth.OptionalAuth", "")
+}
+
+// OptionalScope creates middleware authorizing request if it has a valid access token
+// or API key having the scope and letting it through as anonymous otherwise
+func (a *Auth) OptionalScope(scope rbac.Scope) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return a.optional(next, "middleware.auth.OptionalScope", scope)
+	}
+}
+
+// authorizes request with valid credentials, API keys are accepted if scope is given
+func (a *Auth) optional(next http.Handler, op string, scope rbac.Scope) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, err := a.token(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		ctx, err := a.authenticate(r.Context(), tokenString, scope)
+		if errors.Is(err, errUnavailable) {
+			a.logger(r, op).Error("failed to authorize", slog.String("error", err.Error()))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error("internal error"))
+
+			return
+		}
+		if err != nil {
+			a.logger(r, op).Info("invalid token, proceeding as anonymous", slog.String("error", err.Error()))
+
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireAnonymous rejects requests of already authorized users with 403
+func (a *Auth) RequireAnonymous(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "middleware.auth.RequireAnonymous"
+
+		tokenString, err := a.token(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if _, err := a.authorize(tokenString); err == nil {
+			a.logger(r, op).Info("user already authorized")
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error("user already authorized"))
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequirePermission creates middleware rejecting requests of users
+// whose role doesn't have the permission with 403
+// it must be used after RequireAuth
+func (a *Auth) RequirePermission(perm rbac.Permission) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.auth.RequirePermission"
+
+			if !Can(r.Context(), perm) {
+				a.logger(r, op).Info("permission denied", slog.String("permission", string(perm)))
+
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, response.Error("permission denied"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Can checks if the authorized user has the permission
+func Can(ctx context.Context, perm rbac.Permission) bool {
+	claims, ok := UserClaims(ctx)
+	if !ok {
+		return false
+	}
+
+	return rbac.Can(claims.Role, perm)
+}
+
+// UserClaims returns claims of the authorized user stored in context
+func UserClaims(ctx context.Context) (jwt.UserClaims, bool) {
+	claims, ok := ctx.Value(ctxKey{}).(jwt.UserClaims)
+
+	return claims, ok
+}
+
+// UserLogin returns login of the authorized user stored in context
+func UserLogin(ctx context.Context) (string, bool) {
+	claims, ok := UserClaims(ctx)
+
+	return claims.Login, ok
+}
+
+// APIKey returns API key the request is authorized with
+// it's absent for requests authorized with access token
+func APIKey(ctx context.Context) (*models.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyCtxKey{}).(*models.APIKey)
+
+	return key, ok
+}
+
+// authorizes request with access token or, if scope is given, with API key having it
+// returns context with claims of the authorized user
+func (a *Auth) authenticate(ctx context.Context, tokenString string, scope rbac.Scope) (context.Context, error) {
+	if !apikey.IsKey(tokenString) {
+		claims, err := a.authorize(tokenString)
+		if err != nil {
+			return nil, err
+		}
+
+		return withClaims(ctx, claims), nil
+	}
+
+	if scope == "" {
+		return nil, errKeyNotAccepted
+	}
+
+	claims, key, err := a.authorizeKey(tokenString, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return context.WithValue(withClaims(ctx, claims), apiKeyCtxKey{}, key), nil
+}
+
+// checks API key and its scope and builds claims of its owner
+func (a *Auth) authorizeKey(keyString string, scope rbac.Scope) (jwt.UserClaims, *models.APIKey, error) {
+	const op = "middleware.auth.authorizeKey"
+
+	prefix, ok := apikey.Prefix(keyString)
+	if !ok {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w", op, errInvalidKey)
+	}
+
+	key, err := a.storage.APIKey(prefix)
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w", op, errInvalidKey)
+	}
+	if err != nil {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w: %w", op, errUnavailable, err)
+	}
+
+	if !apikey.Matches(keyString, key.KeyHash) {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w", op, errInvalidKey)
+	}
+
+	now := time.Now()
+
+	if !key.RevokedAt.IsZero() {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w", op, errRevokedKey)
+	}
+
+	if !key.ExpiresAt.IsZero() && now.After(key.ExpiresAt) {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w", op, errExpiredKey)
+	}
+
+	if !slices.Contains(key.Scopes, string(scope)) {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w", op, errInsufficientScope)
+	}
+
+	// keys of banned users stop working as their tokens do
+	_, err = a.storage.ActiveBan(key.UserId)
+	if err == nil {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w", op, errBanned)
+	}
+	if !errors.Is(err, storage.ErrBanNotFound) {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w: %w", op, errUnavailable, err)
+	}
+
+	// role may have changed since the key was created
+	user, err := a.storage.UserByID(key.UserId)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w", op, errInvalidKey)
+	}
+	if err != nil {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w: %w", op, errUnavailable, err)
+	}
+
+	if err := a.storage.TouchAPIKey(key.Id, now); err != nil {
+		return jwt.UserClaims{}, nil, fmt.Errorf("%s: %w: %w", op, errUnavailable, err)
+	}
+
+	claims := jwt.UserClaims{
+		ID:    user.Id,
+		Login: user.Login,
+		Role:  user.Role,
+	}
+
+	return claims, key, nil
+}
+
+// parses access token and checks that its session is still active
+// and its user is not banned, role of the user is taken from storage
+func (a *Auth) authorize(tokenString string) (jwt.UserClaims, error) {
+	const op = "middleware.auth.authorize"
+
+	claims, err := a.tokens.GetTokenClaims(tokenString)
+	if err != nil {
+		return jwt.UserClaims{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// token id is the id of the session it was issued for
+	session, err := a.storage.Session(claims.RegisteredClaims.ID)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return jwt.UserClaims{}, fmt.Errorf("%s: %w", op, errRevokedSession)
+	}
+	if err != nil {
+		return jwt.UserClaims{}, fmt.Errorf("%s: %w: %w", op, errUnavailable, err)
+	}
+
+	if !session.RevokedAt.IsZero() || session.UserId != claims.ID {
+		return jwt.UserClaims{}, fmt.Errorf("%s: %w", op, errRevokedSession)
+	}
+
+	// tokens of banned users stop working immediately
+	_, err = a.storage.ActiveBan(claims.ID)
+	if err == nil {
+		return jwt.UserClaims{}, fmt.Errorf("%s: %w", op, errBanned)
+	}
+	if !errors.Is(err, storage.ErrBanNotFound) {
+		return jwt.UserClaims{}, fmt.Errorf("%s: %w: %w", op, errUnavailable, err)
+	}
+
+	// role in the token may be outdated, demoted users lose their permissions immediately
+	user, err := a.storage.UserByID(claims.ID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return jwt.UserClaims{}, fmt.Errorf("%s: %w", op, errRevokedSession)
+	}
+	if err != nil {
+		return jwt.UserClaims{}, fmt.Errorf("%s: %w: %w", op, errUnavailable, err)
+	}
+
+	claims.Role = user.Role
+
+	if err := a.storage.TouchSession(session.Id, time.Now()); err != nil {
+		return jwt.UserClaims{}, fmt.Errorf("%s: %w: %w", op, errUnavailable, err)
+	}
+
+	return claims, nil
+}
+
+// SessionID returns id of the authorized user's session stored in context
+func SessionID(ctx context.Context) (string, bool) {
+	claims, ok := UserClaims(ctx)
+
+	return claims.RegisteredClaims.ID, ok
+}
+
+// gets access token from Authorization header (RFC 6750)
+// or from legacy Authorization-access header if it's enabled
+func (a *Auth) token(r *http.Request) (string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return "", errMalformedToken
+		}
+
+		return strings.TrimSpace(token), nil
+	}
+
+	if a.legacyHeader {
+		if token := r.Header.Get(legacyHeader); token != "" {
+			return token, nil
+		}
+	}
+
+	return "", errNoToken
+}
+
+// describes why access token or API key was rejected
+func describe(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "the access token expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return "the access token is not valid yet"
+	case errors.Is(err, jwt.ErrTokenWrongAudience):
+		return "the access token is not intended for this service"
+	case errors.Is(err, jwt.ErrTokenWrongIssuer):
+		return "the access token is issued by unknown issuer"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "the access token is malformed"
+	case errors.Is(err, jwt.ErrTokenUnverifiable), errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return "the access token signature is invalid"
+	case errors.Is(err, errRevokedSession):
+		return "the session was revoked"
+	case errors.Is(err, errBanned):
+		return "the account is banned"
+	case errors.Is(err, errInvalidKey):
+		return "the API key is invalid"
+	case errors.Is(err, errRevokedKey):
+		return "the API key was revoked"
+	case errors.Is(err, errExpiredKey):
+		return "the API key expired"
+	case errors.Is(err, errKeyNotAccepted):
+		return "API keys are not accepted for this endpoint"
+	case errors.Is(err, errInsufficientScope):
+		return "the API key doesn't have required scope"
+	default:
+		return "the access token is invalid"
+	}
+}
+
+// sets WWW-Authenticate challenge with the given error code
+func challenge(w http.ResponseWriter, code, description string) {
+	w.Header().Set(
+		"WWW-Authenticate",
+		fmt.Sprintf("Bearer realm=%q, error=%q, error_description=%q", realm, code, description),
+	)
+}
+
+// stores user claims in context
+func withClaims(ctx context.Context, claims jwt.UserClaims) context.Context {
+	return context.WithValue(ctx, ctxKey{}, claims)
+}
+
+// sets up logger for the request
+func (a *Auth) logger(r *http.Request, op string) *slog.Logger {
+	return a.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+}
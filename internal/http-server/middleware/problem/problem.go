@@ -0,0 +1,183 @@
+package problem
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ContentType of error responses in RFC 7807 format
+const ContentType = "application/problem+json"
+
+// problem types are identified by URNs, since the service has no public documentation to link to
+const typePrefix = "urn:ad-market:problem:"
+
+type problemType struct {
+	name  string
+	title string
+}
+
+var types = map[int]problemType{
+	http.StatusBadRequest:          {"bad-request", "Malformed request"},
+	http.StatusUnauthorized:        {"unauthorized", "Authentication required"},
+	http.StatusForbidden:           {"forbidden", "Access denied"},
+	http.StatusNotFound:            {"not-found", "Resource not found"},
+	http.StatusConflict:            {"conflict", "Conflict with current state"},
+	http.StatusUnprocessableEntity: {"validation-error", "Validation failed"},
+	http.StatusTooManyRequests:     {"too-many-requests", "Too many requests"},
+	http.StatusInternalServerError: {"internal-error", "Internal error"},
+}
+
+// New creates a middleware rewriting JSON error responses of handlers into problem details
+// for clients accepting application/problem+json, other clients get responses as they are
+//
+// "error" of the response becomes "detail", "errors" with broken rules of user input
+// and other members are kept as extensions
+func New(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// format of error responses depends on Accept header
+			w.Header().Add("Vary", "Accept")
+
+			if !Accepted(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			pw := &writer{ResponseWriter: w}
+			next.ServeHTTP(pw, r)
+
+			if !pw.buffering {
+				return
+			}
+
+			body, err := convert(r, pw.status, pw.buf.Bytes())
+			if err != nil {
+				log.Error("failed to convert error response",
+					slog.String("op", "middleware.problem.New"),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					slog.String("error", err.Error()),
+				)
+
+				w.WriteHeader(pw.status)
+				w.Write(pw.buf.Bytes())
+
+				return
+			}
+
+			w.Header().Set("Content-Type", ContentType)
+			w.Header().Del("Content-Length")
+			w.WriteHeader(pw.status)
+			w.Write(body)
+		})
+	}
+}
+
+// Accepted reports whether client asked for problem details in Accept header
+func Accepted(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil || mediaType != ContentType {
+			continue
+		}
+
+		// q=0 means the type is not acceptable
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// converts JSON error response to problem details
+func convert(r *http.Request, status int, resp []byte) ([]byte, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(resp, &members); err != nil {
+		return nil, err
+	}
+
+	var detail string
+	if raw, ok := members["error"]; ok {
+		if err := json.Unmarshal(raw, &detail); err != nil {
+			return nil, err
+		}
+	}
+
+	// status of the usual response is a string "Error", in problem details it's HTTP status
+	delete(members, "status")
+	delete(members, "error")
+
+	problem := make(map[string]any, len(members)+6)
+	for name, value := range members {
+		problem[name] = value
+	}
+
+	t, ok := types[status]
+	if ok {
+		problem["type"] = typePrefix + t.name
+		problem["title"] = t.title
+	} else {
+		problem["type"] = "about:blank"
+		problem["title"] = http.StatusText(status)
+	}
+
+	problem["status"] = status
+	problem["instance"] = r.URL.Path
+
+	if detail != "" {
+		problem["detail"] = detail
+	}
+
+	if reqID := middleware.GetReqID(r.Context()); reqID != "" {
+		problem["request_id"] = reqID
+	}
+
+	return json.Marshal(problem)
+}
+
+// writer holds back JSON error responses to convert them after handler is done
+type writer struct {
+	http.ResponseWriter
+
+	status      int
+	wroteHeader bool
+	buffering   bool
+	buf         bytes.Buffer
+}
+
+func (w *writer) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if status >= http.StatusBadRequest && mediaType == "application/json" {
+		w.buffering = true
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.buffering {
+		return w.buf.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
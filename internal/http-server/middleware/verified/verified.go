@@ -0,0 +1,58 @@
+package verified
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/rigbyel/ad-market/internal/http-server/middleware/auth"
+	"github.com/rigbyel/ad-market/internal/lib/response"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type UserProvider interface {
+	UserByID(id int64) (*models.User, error)
+}
+
+// New creates a middleware rejecting users without verified email with 403
+// it must be used after auth.RequireAuth, all requests are passed through if required is false
+func New(log *slog.Logger, userProvider UserProvider, required bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !required {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.verified.New"
+
+			log := log.With(
+				slog.String("op", op),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			claims, _ := auth.UserClaims(r.Context())
+
+			user, err := userProvider.UserByID(claims.ID)
+			if err != nil {
+				log.Error("error finding user", slog.String("error", err.Error()))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, response.Error("internal error"))
+
+				return
+			}
+
+			if user.Email == "" || user.EmailVerifiedAt.IsZero() {
+				log.Info("email is not verified", slog.String("user", user.Login))
+
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, response.Error("email verification required"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
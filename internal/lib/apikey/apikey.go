@@ -0,0 +1,63 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"fmt"
+	"strings"
+
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+)
+
+// keys look like am_<prefix>_<secret>, so they're easy to tell from access tokens
+// and to find in leaked code
+const (
+	Marker = "am_"
+
+	// 5 random bytes are 8 base32 characters
+	prefixSize = 5
+	prefixLen  = len(Marker) + 8
+)
+
+var prefixEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// New generates a new key
+// the key is given to the user, its prefix and hash are kept in storage
+func New() (key, prefix, hash string, err error) {
+	const op = "lib.apikey.New"
+
+	b := make([]byte, prefixSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret, err := opaque.New()
+	if err != nil {
+		return "", "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	prefix = Marker + strings.ToLower(prefixEncoding.EncodeToString(b))
+	key = prefix + "_" + secret
+
+	return key, prefix, opaque.Hash(key), nil
+}
+
+// IsKey reports whether the token looks like an API key rather than an access token
+func IsKey(token string) bool {
+	return strings.HasPrefix(token, Marker)
+}
+
+// Prefix returns public part of the key to look it up by
+func Prefix(key string) (string, bool) {
+	if !IsKey(key) || len(key) <= prefixLen+1 || key[prefixLen] != '_' {
+		return "", false
+	}
+
+	return key[:prefixLen], true
+}
+
+// Matches checks the key against its stored hash
+func Matches(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(opaque.Hash(key)), []byte(hash)) == 1
+}
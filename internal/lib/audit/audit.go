@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+type Saver interface {
+	SaveAuditEntry(entry *models.AuditEntry) error
+}
+
+// Record appends entry to audit log, filling in ip, request id and time from the request
+// failure to save the entry is logged and doesn't fail the request
+func Record(log *slog.Logger, saver Saver, r *http.Request, entry models.AuditEntry) {
+	entry.IP = request.ClientIP(r)
+	entry.RequestId = middleware.GetReqID(r.Context())
+	entry.CreatedAt = time.Now()
+
+	if err := saver.SaveAuditEntry(&entry); err != nil {
+		log.Error("failed to save audit entry",
+			slog.String("event", string(entry.Event)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,221 @@
+package bruteforce
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Counter keeps failed login attempts per key (login or ip address)
+type Counter interface {
+	// AddLoginFailure records failed attempt for the key
+	AddLoginFailure(key string, at time.Time) error
+
+	// LoginFailures returns times of failed attempts for the key since the given time, oldest first
+	// failures before since are not needed anymore and may be discarded
+	LoginFailures(key string, since time.Time) ([]time.Time, error)
+
+	// ResetLoginFailures forgets all failed attempts for the key
+	ResetLoginFailures(key string) error
+
+	// PruneLoginFailures forgets failed attempts of all keys before the given time
+	// it's needed for keys that are never checked again, since only checking discards their old failures
+	PruneLoginFailures(before time.Time) error
+}
+
+// Policy sets how many failed attempts are tolerated for one key
+type Policy struct {
+	// failures older than window are not counted
+	Window time.Duration
+
+	// number of failures after which each next attempt has to wait
+	// BaseDelay, doubled with every failure up to MaxDelay
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+
+	// number of failures within window after which key is locked
+	// for LockoutDuration since the last failure
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+}
+
+// Guard limits failed login attempts per account and per ip address
+type Guard struct {
+	counter Counter
+	login   Policy
+	ip      Policy
+}
+
+// NewGuard creates a new Guard with the given policies for accounts and ip addresses
+func NewGuard(counter Counter, login, ip Policy) *Guard {
+	return &Guard{
+		counter: counter,
+		login:   login,
+		ip:      ip,
+	}
+}
+
+// Check returns how long the client has to wait before the next attempt to log in, zero if it may try now
+func (g *Guard) Check(login, ip string, now time.Time) (time.Duration, error) {
+	const op = "lib.bruteforce.Check"
+
+	loginWait, err := wait(g.counter, loginKey(login), g.login, now)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ipWait, err := wait(g.counter, ipKey(ip), g.ip, now)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return max(loginWait, ipWait), nil
+}
+
+// Fail records failed attempt to log in
+func (g *Guard) Fail(login, ip string, now time.Time) error {
+	const op = "lib.bruteforce.Fail"
+
+	if err := g.counter.AddLoginFailure(loginKey(login), now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := g.counter.AddLoginFailure(ipKey(ip), now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Unlock forgets failed attempts to log in to the account
+// it's called after successful login or password reset
+// failures from ip address are kept, so that one account can't be used to guess others
+func (g *Guard) Unlock(login string) error {
+	const op = "lib.bruteforce.Unlock"
+
+	if err := g.counter.ResetLoginFailures(loginKey(login)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Retention returns how long failures have to be kept to be counted by the policy
+func (p Policy) Retention() time.Duration {
+	// lockout may outlast the window, so failures that caused it are needed too
+	return p.Window + p.LockoutDuration
+}
+
+// RetryAfter formats wait time as Retry-After header value in whole seconds, rounded up
+func RetryAfter(wait time.Duration) string {
+	seconds := int64((wait + time.Second - 1) / time.Second)
+
+	return strconv.FormatInt(max(seconds, 1), 10)
+}
+
+// Limiter limits failed attempts of other kinds per key,
+// e.g. attempts to register taken logins from one ip address
+type Limiter struct {
+	counter Counter
+	name    string
+	policy  Policy
+}
+
+// NewLimiter creates a new Limiter
+// name separates its keys from keys of other limiters sharing the counter
+func NewLimiter(counter Counter, name string, policy Policy) *Limiter {
+	return &Limiter{
+		counter: counter,
+		name:    name,
+		policy:  policy,
+	}
+}
+
+// Check returns how long the key has to wait before the next attempt, zero if it may try now
+func (l *Limiter) Check(key string, now time.Time) (time.Duration, error) {
+	const op = "lib.bruteforce.Limiter.Check"
+
+	d, err := wait(l.counter, l.key(key), l.policy, now)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return d, nil
+}
+
+// Fail records failed attempt for the key
+func (l *Limiter) Fail(key string, now time.Time) error {
+	const op = "lib.bruteforce.Limiter.Fail"
+
+	if err := l.counter.AddLoginFailure(l.key(key), now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (l *Limiter) key(key string) string {
+	return l.name + ":" + key
+}
+
+// computes how long the key has to wait according to the policy
+func wait(counter Counter, key string, policy Policy, now time.Time) (time.Duration, error) {
+	failures, err := counter.LoginFailures(key, now.Add(-policy.Retention()))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(failures) == 0 {
+		return 0, nil
+	}
+
+	last := failures[len(failures)-1]
+
+	// locked if threshold was reached within window ending with the last failure
+	if policy.LockoutThreshold > 0 && countSince(failures, last.Add(-policy.Window)) >= policy.LockoutThreshold {
+		if until := last.Add(policy.LockoutDuration); now.Before(until) {
+			return until.Sub(now), nil
+		}
+	}
+
+	// progressive delay after several recent failures
+	recent := countSince(failures, now.Add(-policy.Window))
+	if policy.DelayAfter > 0 && recent >= policy.DelayAfter {
+		if until := last.Add(delay(policy, recent-policy.DelayAfter)); now.Before(until) {
+			return until.Sub(now), nil
+		}
+	}
+
+	return 0, nil
+}
+
+// computes delay after the given number of failures above the tolerated ones
+func delay(policy Policy, excess int) time.Duration {
+	d := policy.BaseDelay
+	for i := 0; i < excess && d < policy.MaxDelay; i++ {
+		d *= 2
+	}
+
+	return min(d, policy.MaxDelay)
+}
+
+// counts failures at or after the given time
+func countSince(failures []time.Time, since time.Time) int {
+	count := 0
+	for _, f := range failures {
+		if !f.Before(since) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func loginKey(login string) string {
+	return "login:" + login
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
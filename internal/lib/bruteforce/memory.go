@@ -0,0 +1,79 @@
+package bruteforce
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryCounter keeps failed attempts in memory
+// they are lost on restart and aren't shared between instances of the service
+type MemoryCounter struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+}
+
+// NewMemoryCounter creates a new empty MemoryCounter
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{
+		failures: make(map[string][]time.Time),
+	}
+}
+
+func (c *MemoryCounter) AddLoginFailure(key string, at time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures[key] = append(c.failures[key], at)
+
+	return nil
+}
+
+func (c *MemoryCounter) LoginFailures(key string, since time.Time) ([]time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// discarding old failures
+	failures := c.failures[key]
+	for len(failures) > 0 && failures[0].Before(since) {
+		failures = failures[1:]
+	}
+
+	if len(failures) == 0 {
+		delete(c.failures, key)
+
+		return nil, nil
+	}
+
+	c.failures[key] = failures
+
+	return append([]time.Time(nil), failures...), nil
+}
+
+func (c *MemoryCounter) ResetLoginFailures(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.failures, key)
+
+	return nil
+}
+
+func (c *MemoryCounter) PruneLoginFailures(before time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, failures := range c.failures {
+		// failures are kept in order they were added, so the last one is the newest
+		if failures[len(failures)-1].Before(before) {
+			delete(c.failures, key)
+			continue
+		}
+
+		for len(failures) > 0 && failures[0].Before(before) {
+			failures = failures[1:]
+		}
+		c.failures[key] = failures
+	}
+
+	return nil
+}
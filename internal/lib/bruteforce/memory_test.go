@@ -0,0 +1,34 @@
+package bruteforce_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rigbyel/ad-market/internal/lib/bruteforce"
+)
+
+func TestMemoryCounterPrune(t *testing.T) {
+	c := bruteforce.NewMemoryCounter()
+	now := time.Unix(1000, 0)
+
+	// the key is never checked again, so only pruning removes its failures
+	c.AddLoginFailure("stale", now.Add(-2*time.Hour))
+	c.AddLoginFailure("mixed", now.Add(-2*time.Hour))
+	c.AddLoginFailure("mixed", now.Add(-time.Minute))
+
+	if err := c.PruneLoginFailures(now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// checking with an earlier since shows what pruning kept
+	since := now.Add(-24 * time.Hour)
+
+	if failures, _ := c.LoginFailures("stale", since); len(failures) != 0 {
+		t.Fatalf("stale failures kept: %v", failures)
+	}
+
+	failures, _ := c.LoginFailures("mixed", since)
+	if len(failures) != 1 || !failures[0].Equal(now.Add(-time.Minute)) {
+		t.Fatalf("unexpected failures after pruning: %v", failures)
+	}
+}
@@ -0,0 +1,61 @@
+package credentials
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/rigbyel/ad-market/internal/lib/passhash"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// Manager hashes and checks passwords of users
+type Manager struct {
+	hashes *passhash.Manager
+
+	// hash of random password with the current parameters, compared for unknown users
+	dummyHash []byte
+}
+
+// New creates a new Manager
+func New(hashes *passhash.Manager) (*Manager, error) {
+	const op = "lib.credentials.New"
+
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	dummyHash, err := hashes.Hash(string(password))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Manager{
+		hashes:    hashes,
+		dummyHash: dummyHash,
+	}, nil
+}
+
+// Hash hashes new password of the user
+func (m *Manager) Hash(password string) ([]byte, error) {
+	return m.hashes.Hash(password)
+}
+
+// Verify checks the password of the user
+// user is nil if there's no user with the requested login, then password is compared
+// with dummy hash anyway, so that response time doesn't reveal whether the user exists
+// rehash is true if the password is correct, but its hash is outdated and should be replaced
+func (m *Manager) Verify(user *models.User, password string) (ok, rehash bool) {
+	if user == nil {
+		_, _, _ = m.hashes.Verify(m.dummyHash, password)
+
+		return false, false
+	}
+
+	ok, rehash, err := m.hashes.Verify(user.PassHash, password)
+	if err != nil {
+		return false, false
+	}
+
+	return ok, rehash
+}
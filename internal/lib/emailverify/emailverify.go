@@ -0,0 +1,135 @@
+package emailverify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rigbyel/ad-market/internal/lib/mailer"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid verification token")
+	ErrExpiredToken = errors.New("verification token expired")
+)
+
+// separates signatures of verification links from other values signed with the same secret
+const purpose = "email-verify"
+
+// Claims is the content of verification token
+// the email is included, so that the link stops working once the user changes it
+type Claims struct {
+	UserID    int64  `json:"uid"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Verifier sends and checks email verification links signed with HMAC-SHA256
+type Verifier struct {
+	secret  []byte
+	ttl     time.Duration
+	linkURL string
+	mailer  mailer.Mailer
+}
+
+// New creates a new Verifier
+// token is appended to linkURL as token query parameter
+func New(secret []byte, ttl time.Duration, linkURL string, mailer mailer.Mailer) *Verifier {
+	return &Verifier{
+		secret:  secret,
+		ttl:     ttl,
+		linkURL: linkURL,
+		mailer:  mailer,
+	}
+}
+
+// Send emails verification link to the user
+func (v *Verifier) Send(userID int64, email string) error {
+	const op = "lib.emailverify.Send"
+
+	token, err := v.token(Claims{
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(v.ttl).Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	link, err := url.Parse(v.linkURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	body := fmt.Sprintf(
+		"Follow the link to verify your email: %s\nThe link is valid for %s. If you didn't use this email on ad-market, ignore this message.",
+		link.String(),
+		v.ttl,
+	)
+
+	if err := v.mailer.Send(email, "Verify your email", body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Check verifies signature and expiration of the token and returns its claims
+func (v *Verifier) Check(token string, now time.Time) (Claims, error) {
+	const op = "lib.emailverify.Check"
+
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, v.sign(payload)) {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if now.Unix() >= claims.ExpiresAt {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrExpiredToken)
+	}
+
+	return claims, nil
+}
+
+// builds signed token: base64url(claims) + "." + base64url(signature)
+func (v *Verifier) token(claims Claims) (string, error) {
+	raw, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(v.sign(payload)), nil
+}
+
+func (v *Verifier) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(purpose + "." + payload))
+
+	return mac.Sum(nil)
+}
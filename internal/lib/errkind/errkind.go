@@ -0,0 +1,49 @@
+// Package errkind classifies errors of storage, validation and other layers,
+// so that every handler maps them to the same HTTP status
+package errkind
+
+import (
+	"errors"
+)
+
+var (
+	// Malformed request can't be understood, e.g. query parameter isn't a number
+	Malformed = errors.New("malformed request")
+	// Invalid request is understood, but breaks rules of user input
+	Invalid = errors.New("invalid input")
+	// NotFound request refers to something that doesn't exist
+	NotFound = errors.New("not found")
+	// Conflict request conflicts with the current state, e.g. login is taken
+	Conflict = errors.New("conflict")
+)
+
+// Error is an error of a kind with a message safe to show to the user
+type Error struct {
+	Kind error
+	msg  string
+}
+
+// New creates an error of the kind
+func New(kind error, msg string) error {
+	return &Error{Kind: kind, msg: msg}
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// Is matches the kind, so errors.Is(err, errkind.NotFound) holds through wrapping
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Message returns the message of the first typed error in the chain and
+// false if there's none
+func Message(err error) (string, bool) {
+	var e *Error
+	if !errors.As(err, &e) {
+		return "", false
+	}
+
+	return e.msg, true
+}
@@ -0,0 +1,139 @@
+package feed
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/rigbyel/ad-market/internal/lib/errkind"
+	"github.com/rigbyel/ad-market/internal/models"
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+)
+
+var (
+	ErrInvalidSort  = errkind.New(errkind.Malformed, "sort should be one of: new, old, priceUp, priceDown")
+	ErrInvalidPage  = errkind.New(errkind.Malformed, "page should be a number")
+	ErrPageNotFound = errkind.New(errkind.NotFound, "nothing found")
+)
+
+type Advert struct {
+	Id       int64  `json:"id"`
+	Header   string `json:"header"`
+	Body     string `json:"body"`
+	ImageURL string `json:"image_url,omitempty"`
+	Price    int    `json:"price"`
+	Author   string `json:"author"`
+	IsAuthor bool   `json:"is_author,omitempty"`
+}
+
+// PrepareAdverts prepares adverts according to filters from query parameters (sort type, sort order, page, etc)
+func PrepareAdverts(r *http.Request, adverts *[]models.Advert, isAuthorized bool, authorLogin string) ([]Advert, error) {
+	const op = "lib.feed.PrepareAdverts"
+
+	page, err := Page(r, adverts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var pageAdverts []Advert
+
+	for _, ad := range page {
+		filteredAd := Advert{
+			Id:       ad.Id,
+			Header:   ad.Header,
+			Body:     ad.Body,
+			ImageURL: ad.ImageURL,
+			Price:    ad.Price,
+			Author:   ad.AuthorLogin,
+		}
+
+		if isAuthorized && ad.AuthorLogin == authorLogin {
+			filteredAd.IsAuthor = true
+		}
+
+		pageAdverts = append(pageAdverts, filteredAd)
+	}
+
+	return pageAdverts, nil
+}
+
+// Page sorts adverts and extracts the page requested in query parameters
+func Page(r *http.Request, adverts *[]models.Advert) ([]models.Advert, error) {
+	const op = "lib.feed.Page"
+
+	// get sorting type from query parameters
+	sortType := r.URL.Query().Get("sort")
+	if sortType == "" {
+		sortType = "new"
+	}
+
+	// sort adverts with a given sorting type
+	err := SortAdverts(*adverts, sortType)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// getting page of adverts feed from query parameters
+	pageStr := r.URL.Query().Get("page")
+	if pageStr == "" {
+		pageStr = "1"
+	}
+
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidPage)
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+
+	// check if there's any adverts on the given page
+	if (page-1)*constraints.AdvertsOnPage >= len(*adverts) {
+		return nil, fmt.Errorf("%s: page %d: %w", op, page, ErrPageNotFound)
+	}
+
+	// extracting page from the whole feed of adverts
+	start := constraints.AdvertsOnPage * (page - 1)
+	end := min(start+constraints.AdvertsOnPage, len(*adverts))
+
+	return (*adverts)[start:end], nil
+}
+
+// SortAdverts sorts adverts list with a given sorting type
+func SortAdverts(adverts []models.Advert, sortType string) error {
+	const op = "lib.feed.SortAdverts"
+
+	switch sortType {
+
+	// ascending price
+	case "priceUp":
+		sort.Slice(adverts, func(i, j int) bool {
+			return adverts[i].Price < adverts[j].Price
+		})
+
+	// descending price
+	case "priceDown":
+		sort.Slice(adverts, func(i, j int) bool {
+			return adverts[i].Price > adverts[j].Price
+		})
+
+	// newest adverts in the beginning
+	case "new":
+		sort.Slice(adverts, func(i, j int) bool {
+			return adverts[i].Date.Compare(adverts[j].Date) == 1
+		})
+
+	// oldest adverts in the begginning
+	case "old":
+		sort.Slice(adverts, func(i, j int) bool {
+			return adverts[i].Date.Compare(adverts[j].Date) == -1
+		})
+
+	default:
+		return fmt.Errorf("%s: %w", op, ErrInvalidSort)
+	}
+
+	return nil
+}
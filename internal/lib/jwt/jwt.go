@@ -1,30 +1,145 @@
 package jwt
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/rigbyel/ad-market/internal/models"
 )
 
+// errors returned by GetTokenClaims, so that callers can tell why token was rejected
+var (
+	ErrTokenMalformed        = errors.New("token is malformed")
+	ErrTokenUnverifiable     = errors.New("token is signed with unknown key")
+	ErrTokenSignatureInvalid = errors.New("token signature is invalid")
+	ErrTokenExpired          = errors.New("token is expired")
+	ErrTokenNotValidYet      = errors.New("token is not valid yet")
+	ErrTokenWrongIssuer      = errors.New("token has wrong issuer")
+	ErrTokenWrongAudience    = errors.New("token has wrong audience")
+	ErrTokenInvalid          = errors.New("token is invalid")
+)
+
 type UserClaims struct {
 	jwt.RegisteredClaims
 	ID    int64
 	Login string
+	Role  models.Role
+}
+
+// Options of registered claims set on issue and required on parse
+type Options struct {
+	Issuer   string
+	Audience string
+
+	// allowed clock skew between services when checking exp, nbf and iat
+	Leeway time.Duration
+}
+
+// Manager issues and verifies tokens with a set of keys
+// tokens are signed with the primary key, retired keys are only used for verification
+type Manager struct {
+	opts    Options
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewManager creates Manager signing tokens with the key signingKeyID
+// other keys are kept for verifying tokens issued before rotation
+func NewManager(opts Options, signingKeyID string, keys ...*Key) (*Manager, error) {
+	const op = "lib.jwt.NewManager"
+
+	m := &Manager{
+		opts: opts,
+		keys: make(map[string]*Key, len(keys)),
+	}
+
+	for _, k := range keys {
+		m.keys[k.ID] = k
+	}
+
+	signing, ok := m.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: %s", op, ErrSigningKeyAbsent, signingKeyID)
+	}
+
+	if signing.private == nil {
+		return nil, fmt.Errorf("%s: %w: %s", op, ErrNoPrivateKey, signingKeyID)
+	}
+
+	m.signing = signing
+
+	return m, nil
+}
+
+// LoadManager loads keys from PEM files and creates Manager with them
+func LoadManager(opts Options, signingKeyID string, files []KeyFile) (*Manager, error) {
+	const op = "lib.jwt.LoadManager"
+
+	var keys []*Key
+
+	for _, f := range files {
+		key, err := LoadKey(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	m, err := NewManager(opts, signingKeyID, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return m, nil
+}
+
+// NewHMACManager creates Manager signing tokens with shared HS256 secret
+func NewHMACManager(opts Options, secret string) *Manager {
+	key := &Key{
+		Method:  jwt.SigningMethodHS256,
+		private: []byte(secret),
+		public:  []byte(secret),
+	}
+
+	return &Manager{
+		opts:    opts,
+		signing: key,
+		keys:    map[string]*Key{key.ID: key},
+	}
 }
 
 // NewToken creates new JWT token for given user
-func NewToken(user models.User, secret string, duration time.Duration) (string, error) {
+// id of the user's session is used as token id (jti)
+func (m *Manager) NewToken(user models.User, sessionID string, duration time.Duration) (string, error) {
 	const op = "lib.jwt.NewToken"
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaims{
-		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration))},
-		ID:               user.Id,
-		Login:            user.Login,
+	now := time.Now()
+
+	token := jwt.NewWithClaims(m.signing.Method, UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
+			Issuer:    m.opts.Issuer,
+			Subject:   strconv.FormatInt(user.Id, 10),
+			Audience:  jwt.ClaimStrings{m.opts.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+		ID:    user.Id,
+		Login: user.Login,
+		Role:  user.Role,
 	})
 
-	tokenString, err := token.SignedString([]byte(secret))
+	if m.signing.ID != "" {
+		token.Header["kid"] = m.signing.ID
+	}
+
+	tokenString, err := token.SignedString(m.signing.private)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
@@ -33,24 +148,93 @@ func NewToken(user models.User, secret string, duration time.Duration) (string,
 }
 
 // GetTokenClaims parses claims from token string
-func GetTokenClaims(tokenString, secret string) (UserClaims, error) {
+// and validates registered claims against Manager options
+func (m *Manager) GetTokenClaims(tokenString string) (UserClaims, error) {
 	const op = "lib.jwt.GetTokenClaims"
 	var claims UserClaims
 
-	token, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("%s: wrong token signing method", op)
-		}
-
-		return []byte(secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &claims, m.keyFunc,
+		jwt.WithIssuer(m.opts.Issuer),
+		jwt.WithAudience(m.opts.Audience),
+		jwt.WithLeeway(m.opts.Leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
 
 	if err != nil {
-		return UserClaims{}, fmt.Errorf("%s: %w", op, err)
+		return UserClaims{}, fmt.Errorf("%s: %w: %w", op, classify(err), err)
 	}
 	if !token.Valid {
-		return UserClaims{}, fmt.Errorf("invalid token")
+		return UserClaims{}, fmt.Errorf("%s: %w", op, ErrTokenInvalid)
+	}
+
+	// every access token is bound to a session
+	if claims.RegisteredClaims.ID == "" {
+		return UserClaims{}, fmt.Errorf("%s: %w: no token id", op, ErrTokenMalformed)
 	}
 
 	return claims, nil
 }
+
+// maps errors of jwt library to errors of this package
+func classify(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed), errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenUnverifiable
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return ErrTokenSignatureInvalid
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenWrongIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenWrongAudience
+	default:
+		return ErrTokenInvalid
+	}
+}
+
+// JWKS returns public keys for verifying tokens
+// keys of symmetric algorithms are never published
+func (m *Manager) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	for _, k := range m.keys {
+		jwk, err := k.jwk()
+		if err != nil {
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks
+}
+
+// finds key the token was signed with by its kid
+func (m *Manager) keyFunc(t *jwt.Token) (interface{}, error) {
+	const op = "lib.jwt.keyFunc"
+
+	kid, _ := t.Header["kid"].(string)
+
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownKey, kid)
+	}
+
+	// algorithm from token header must match the key, otherwise
+	// public key could be used as HMAC secret
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("%s: wrong token signing method", op)
+	}
+
+	return key.public, nil
+}
@@ -0,0 +1,140 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey       = errors.New("unknown signing key")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrNoPrivateKey     = errors.New("signing key has no private part")
+	ErrUnsupportedKey   = errors.New("unsupported key type")
+	ErrSigningKeyAbsent = errors.New("signing key is not among configured keys")
+)
+
+// Key is a key used to sign or verify tokens
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	// private key is nil for verify-only keys
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// KeyFile describes a PEM file with a key identified by kid
+type KeyFile struct {
+	ID        string
+	Algorithm string
+	Path      string
+}
+
+// LoadKey reads key from PEM file
+// file may contain a private key or, for verify-only keys, a public key
+func LoadKey(f KeyFile) (*Key, error) {
+	const op = "lib.jwt.LoadKey"
+
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key := &Key{ID: f.ID}
+
+	switch f.Algorithm {
+	case "RS256", "RS384", "RS512":
+		key.Method = jwt.GetSigningMethod(f.Algorithm)
+
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.private = private
+			key.public = &private.PublicKey
+
+			break
+		}
+
+		public, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %s: %w", op, f.ID, err)
+		}
+
+		key.public = public
+
+	case "EdDSA":
+		key.Method = jwt.SigningMethodEdDSA
+
+		if private, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			key.private = private
+			key.public = private.(ed25519.PrivateKey).Public()
+
+			break
+		}
+
+		public, err := jwt.ParseEdPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %s: %w", op, f.ID, err)
+		}
+
+		key.public = public
+
+	default:
+		return nil, fmt.Errorf("%s: key %s: %w: %s", op, f.ID, ErrUnsupportedAlg, f.Algorithm)
+	}
+
+	return key, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a set of public keys other services use to verify tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// returns public part of the key in JWK format
+func (k *Key) jwk() (JWK, error) {
+	jwk := JWK{
+		Kid: k.ID,
+		Use: "sig",
+		Alg: k.Method.Alg(),
+	}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+
+	default:
+		return JWK{}, ErrUnsupportedKey
+	}
+
+	return jwk, nil
+}
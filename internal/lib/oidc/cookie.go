@@ -0,0 +1,51 @@
+package oidc
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+)
+
+// name of cookie binding authorization request to the browser that started it
+const stateCookie = "oidc_state"
+
+// SetStateCookie remembers state of authorization request in the browser for ttl
+// callback accepts the state only from the same browser, so that a callback link
+// of someone else's request can't log the user in or link identity to a foreign account
+func SetStateCookie(w http.ResponseWriter, r *http.Request, provider, state string, ttl time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    state,
+		Path:     callbackPath(provider),
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// provider redirects back with top-level GET, which carries lax cookies
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// CheckStateCookie reports whether state of callback was issued to this browser
+// the cookie is removed, since the state can be used only once anyway
+func CheckStateCookie(w http.ResponseWriter, r *http.Request, provider, state string) bool {
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil {
+		return false
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Path:     callbackPath(provider),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return state != "" && subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) == 1
+}
+
+// path of the service's callback endpoint of the provider
+func callbackPath(provider string) string {
+	return "/oidc/" + provider + "/callback"
+}
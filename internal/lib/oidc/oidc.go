@@ -0,0 +1,327 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+)
+
+// allowed difference between clocks of the service and provider
+const leeway = time.Minute
+
+var (
+	ErrDiscovery      = errors.New("failed to discover provider configuration")
+	ErrExchange       = errors.New("failed to exchange authorization code")
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// Config describes OpenID Connect provider the service is registered with
+type Config struct {
+	// name of the provider in urls of the service
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	// address of the service's callback endpoint registered with the provider
+	RedirectURL string
+
+	// scopes requested in addition to openid
+	Scopes []string
+}
+
+// Identity is a user authenticated by provider
+type Identity struct {
+	Provider string
+
+	// identifier of the user unique within the provider, it never changes
+	Subject string
+
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+// AuthRequest is a started authorization request
+// state, nonce and verifier have to be kept until the user returns to callback
+type AuthRequest struct {
+	// address of the provider's authorization endpoint the user should be sent to
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// Client performs authorization code flow with PKCE against one provider
+type Client struct {
+	cfg  Config
+	http *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     *keySet
+}
+
+// Providers are clients of configured providers by their names
+type Providers map[string]*Client
+
+// provider metadata from its discovery document
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+}
+
+// New creates a new Client
+// provider configuration is discovered on first use
+func New(cfg Config, httpClient *http.Client) *Client {
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+
+	return &Client{
+		cfg:  cfg,
+		http: httpClient,
+	}
+}
+
+// Name returns name of the provider
+func (c *Client) Name() string {
+	return c.cfg.Name
+}
+
+// NewAuthRequest starts authorization request with random state, nonce and PKCE verifier
+func (c *Client) NewAuthRequest(ctx context.Context) (*AuthRequest, error) {
+	const op = "lib.oidc.NewAuthRequest"
+
+	var secrets [3]string
+	for i := range secrets {
+		secret, err := opaque.New()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		secrets[i] = secret
+	}
+
+	req := &AuthRequest{
+		State:    secrets[0],
+		Nonce:    secrets[1],
+		Verifier: secrets[2],
+	}
+
+	authURL, err := c.AuthURL(ctx, req.State, req.Nonce, req.Verifier)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	req.URL = authURL
+
+	return req, nil
+}
+
+// AuthURL returns address of the provider's authorization endpoint the user should be sent to
+// state and nonce should be random and kept until callback along with PKCE verifier
+func (c *Client) AuthURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	const op = "lib.oidc.AuthURL"
+
+	md, err := c.discover(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	scopes := append([]string{"openid"}, c.cfg.Scopes...)
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", c.cfg.ClientID)
+	q.Set("redirect_uri", c.cfg.RedirectURL)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", Challenge(verifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return md.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange exchanges authorization code for tokens and returns the user's identity from id token
+// id token is checked to be signed by the provider for this client and to have the expected nonce
+func (c *Client) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	const op = "lib.oidc.Exchange"
+
+	md, err := c.discover(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	var tokens tokenResponse
+	if err := c.doJSON(req, &tokens); err != nil && tokens.Error == "" {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrExchange, err)
+	}
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("%s: %w: %s %s", op, ErrExchange, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%s: %w: no id token in response", op, ErrExchange)
+	}
+
+	claims, err := c.verify(ctx, md, tokens.IDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrInvalidIDToken, err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%s: %w: nonce mismatch", op, ErrInvalidIDToken)
+	}
+
+	return &Identity{
+		Provider:          c.cfg.Name,
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		PreferredUsername: claims.PreferredUsername,
+		Name:              claims.Name,
+	}, nil
+}
+
+// parses id token and checks its signature, issuer, audience and lifetime
+func (c *Client) verify(ctx context.Context, md *metadata, idToken string) (*idTokenClaims, error) {
+	var claims idTokenClaims
+
+	_, err := jwt.ParseWithClaims(
+		idToken,
+		&claims,
+		func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+
+			return c.key(ctx, md, kid)
+		},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(md.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(leeway),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("no subject")
+	}
+
+	// token issued for several clients should name this one as the party it was issued to
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != c.cfg.ClientID {
+		return nil, errors.New("token is authorized for another party")
+	}
+
+	return &claims, nil
+}
+
+// returns provider metadata, fetching it on first call
+func (c *Client) discover(ctx context.Context) (*metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.metadata != nil {
+		return c.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	var md metadata
+	if err := c.doJSON(req, &md); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	// provider must not impersonate another issuer
+	if strings.TrimSuffix(md.Issuer, "/") != c.cfg.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q doesn't match configured one", ErrDiscovery, md.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete provider metadata", ErrDiscovery)
+	}
+
+	c.metadata = &md
+
+	return c.metadata, nil
+}
+
+// sends request and decodes JSON response
+// error responses are decoded too, as token endpoint describes errors in body
+func (c *Client) doJSON(req *http.Request, v any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	decodeErr := json.Unmarshal(body, v)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return decodeErr
+}
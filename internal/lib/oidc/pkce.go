@@ -0,0 +1,14 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// Challenge derives PKCE code challenge from verifier with S256 method (RFC 7636)
+// verifier should be random string of 43 to 128 url-safe characters, e.g. from opaque.New
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,119 @@
+package passhash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idPrefix = "$argon2id$"
+
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// Argon2id hashes passwords with Argon2id
+// hashes are encoded in PHC string format: $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+type Argon2id struct {
+	// memory in KiB
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+func NewArgon2id(memory, time uint32, threads uint8) *Argon2id {
+	return &Argon2id{
+		Memory:  memory,
+		Time:    time,
+		Threads: threads,
+	}
+}
+
+func (a *Argon2id) Hash(password string) ([]byte, error) {
+	const op = "lib.passhash.Argon2id.Hash"
+
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, argon2KeyLen)
+
+	return []byte(encodeArgon2id(*a, salt, key)), nil
+}
+
+func (a *Argon2id) Verify(encoded []byte, password string) (bool, error) {
+	const op = "lib.passhash.Argon2id.Verify"
+
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// hash is checked with parameters it was made with, not the current ones
+	actual := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (a *Argon2id) Recognizes(encoded []byte) bool {
+	return bytes.HasPrefix(encoded, []byte(argon2idPrefix))
+}
+
+func (a *Argon2id) NeedsRehash(encoded []byte) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+
+	return err != nil || params != *a
+}
+
+func encodeArgon2id(params Argon2id, salt, key []byte) string {
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		params.Memory,
+		params.Time,
+		params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2id(encoded []byte) (params Argon2id, salt, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(string(encoded), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads)
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	return params, salt, key, nil
+}
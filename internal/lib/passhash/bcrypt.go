@@ -0,0 +1,60 @@
+package passhash
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt hashes passwords with bcrypt
+// its hashes already contain version and cost: $2a$10$<salt and hash>
+type Bcrypt struct {
+	Cost int
+}
+
+func NewBcrypt(cost int) *Bcrypt {
+	return &Bcrypt{Cost: cost}
+}
+
+func (b *Bcrypt) Hash(password string) ([]byte, error) {
+	const op = "lib.passhash.Bcrypt.Hash"
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hash, nil
+}
+
+func (b *Bcrypt) Verify(encoded []byte, password string) (bool, error) {
+	const op = "lib.passhash.Bcrypt.Verify"
+
+	err := bcrypt.CompareHashAndPassword(encoded, []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+func (b *Bcrypt) Recognizes(encoded []byte) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if bytes.HasPrefix(encoded, []byte(prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *Bcrypt) NeedsRehash(encoded []byte) bool {
+	cost, err := bcrypt.Cost(encoded)
+
+	return err != nil || cost != b.Cost
+}
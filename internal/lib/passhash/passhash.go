@@ -0,0 +1,75 @@
+package passhash
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+
+// Hasher hashes passwords with one algorithm
+// hashes are encoded with the algorithm and its parameters, so they can be checked after parameters change
+type Hasher interface {
+	Hash(password string) ([]byte, error)
+	Verify(encoded []byte, password string) (bool, error)
+
+	// Recognizes reports whether the hash was made with this algorithm
+	Recognizes(encoded []byte) bool
+
+	// NeedsRehash reports whether the hash was made with parameters other than current ones
+	NeedsRehash(encoded []byte) bool
+}
+
+// Manager hashes new passwords with the current hasher and checks existing
+// hashes with whichever hasher made them
+type Manager struct {
+	current Hasher
+	hashers []Hasher
+}
+
+// New creates a new Manager
+// legacy hashers are only used for checking hashes made before current one was configured
+func New(current Hasher, legacy ...Hasher) *Manager {
+	return &Manager{
+		current: current,
+		hashers: append([]Hasher{current}, legacy...),
+	}
+}
+
+// Hash hashes password with the current hasher
+func (m *Manager) Hash(password string) ([]byte, error) {
+	const op = "lib.passhash.Hash"
+
+	hash, err := m.current.Hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hash, nil
+}
+
+// Verify checks password against the hash
+// rehash is true if the password is correct, but the hash is made with other algorithm
+// or parameters, then it should be replaced with a new one
+func (m *Manager) Verify(encoded []byte, password string) (ok, rehash bool, err error) {
+	const op = "lib.passhash.Verify"
+
+	for _, h := range m.hashers {
+		if !h.Recognizes(encoded) {
+			continue
+		}
+
+		ok, err := h.Verify(encoded, password)
+		if err != nil {
+			return false, false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if !ok {
+			return false, false, nil
+		}
+
+		return true, h != m.current || h.NeedsRehash(encoded), nil
+	}
+
+	return false, false, fmt.Errorf("%s: %w", op, ErrUnknownAlgorithm)
+}
@@ -0,0 +1,99 @@
+package rbac
+
+import "github.com/rigbyel/ad-market/internal/models"
+
+type Permission string
+
+const (
+	// hide adverts of any user from feed
+	PermHideAnyAdvert Permission = "adverts:hide-any"
+
+	// review reported adverts and ban their authors
+	PermModerate Permission = "adverts:moderate"
+
+	// change roles of other users
+	PermManageRoles Permission = "users:manage-roles"
+
+	// suspend and ban users
+	PermBanUsers Permission = "users:ban"
+
+	// read audit log
+	PermReadAudit Permission = "audit:read"
+)
+
+// Scope limits what an API key can be used for
+// access tokens of sessions are not limited by scopes
+type Scope string
+
+const (
+	// read feed and public profiles and adverts of users
+	ScopeFeedRead Scope = "feed:read"
+
+	// read own account
+	ScopeAccountRead Scope = "account:read"
+
+	// read own adverts including drafts
+	ScopeAdvertsRead Scope = "adverts:read"
+
+	// post adverts
+	ScopeAdvertsWrite Scope = "adverts:write"
+)
+
+var scopes = map[Scope]bool{
+	ScopeFeedRead:     true,
+	ScopeAccountRead:  true,
+	ScopeAdvertsRead:  true,
+	ScopeAdvertsWrite: true,
+}
+
+// ValidScope checks if the scope exists
+func ValidScope(scope Scope) bool {
+	return scopes[scope]
+}
+
+// permissions granted to each role
+// every role has permissions of the roles below it
+var rolePermissions = map[models.Role][]Permission{
+	models.RoleUser: {},
+	models.RoleModerator: {
+		PermHideAnyAdvert,
+		PermModerate,
+	},
+	models.RoleAdmin: {
+		PermHideAnyAdvert,
+		PermModerate,
+		PermManageRoles,
+		PermBanUsers,
+		PermReadAudit,
+	},
+}
+
+// rank of each role, higher roles can act on users of lower ones
+var roleRanks = map[models.Role]int{
+	models.RoleUser:      0,
+	models.RoleModerator: 1,
+	models.RoleAdmin:     2,
+}
+
+// Outranks checks if users of the role can act on users of the target role, e.g. ban them
+func Outranks(role, target models.Role) bool {
+	return roleRanks[role] > roleRanks[target]
+}
+
+// Can checks if the role has the permission
+func Can(role models.Role, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidRole checks if the role exists
+func ValidRole(role models.Role) bool {
+	_, ok := rolePermissions[role]
+
+	return ok
+}
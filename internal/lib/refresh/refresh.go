@@ -0,0 +1,32 @@
+package refresh
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// New creates a refresh token of the given session for the user
+// returns the token to give to the client and its record to keep in storage
+func New(userID int64, sessionID string, ttl time.Duration) (string, *models.RefreshToken, error) {
+	const op = "lib.refresh.New"
+
+	token, err := opaque.New()
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+
+	rt := &models.RefreshToken{
+		UserId:    userID,
+		SessionId: sessionID,
+		TokenHash: opaque.Hash(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	return token, rt, nil
+}
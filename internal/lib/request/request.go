@@ -1,8 +1,95 @@
 package request
 
+import (
+	"log/slog"
+	"net"
+	"net/http"
+)
+
 type UserRequest struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+
+	// optional email given on registration
+	Email string `json:"email,omitempty"`
+}
+
+// LogValue keeps password and email out of logs
+func (r UserRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("login", r.Login),
+		slog.Bool("email", r.Email != ""),
+	)
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+type ReportRequest struct {
+	Reason  string `json:"reason"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type ModerationRequest struct {
+	Reason string `json:"reason"`
+}
+
+type BanRequest struct {
+	Reason string `json:"reason"`
+
+	// duration of suspension, e.g. "72h", empty for permanent ban
+	Duration string `json:"duration,omitempty"`
+}
+
+type AppealRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	Appeal   string `json:"appeal"`
+}
+
+type PasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordRequest struct {
+	Login string `json:"login"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorLoginRequest completes login with either authenticator code or recovery code
+type TwoFactorLoginRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+}
+
+type AccountDeleteRequest struct {
+	Password string `json:"password"`
+}
+
+// APIKeyRequest creates API key, empty ExpiresIn means the key doesn't expire
+type APIKeyRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn string   `json:"expires_in,omitempty"`
 }
 
 type AdvertRequest struct {
@@ -10,4 +97,23 @@ type AdvertRequest struct {
 	Body     string `json:"body,omitempty"`
 	Price    int    `json:"price"`
 	ImageURL string `json:"image_url"`
+	Status   string `json:"status,omitempty"`
+}
+
+type ProfileRequest struct {
+	Bio       *string `json:"bio,omitempty"`
+	AvatarURL *string `json:"avatar_url,omitempty"`
+
+	// empty email removes it
+	Email *string `json:"email,omitempty"`
+}
+
+// ClientIP returns ip address of the client that sent the request
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
 }
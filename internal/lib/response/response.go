@@ -1,15 +1,22 @@
 package response
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/rigbyel/ad-market/internal/lib/errkind"
+	"github.com/rigbyel/ad-market/internal/lib/validate"
 )
 
 type Response struct {
 	Status string `json:"status"` // Error, Ok
 	Error  string `json:"error,omitempty"`
+
+	// broken rules of user input by field
+	Errors validate.Errors `json:"errors,omitempty"`
 }
 
 const (
@@ -30,6 +37,42 @@ func Error(msg string) Response {
 	}
 }
 
+// Invalid builds error response for user input breaking the rules
+func Invalid(errs validate.Errors) Response {
+	return Response{
+		Status: StatusError,
+		Error:  errs.Error(),
+		Errors: errs,
+	}
+}
+
+// StatusOf maps typed error to HTTP status, errors of unknown kind are internal
+func StatusOf(err error) int {
+	switch {
+	case errors.Is(err, errkind.Malformed):
+		return http.StatusBadRequest
+	case errors.Is(err, errkind.Invalid):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, errkind.NotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errkind.Conflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ErrorOf builds error response with message of typed error, so that
+// details of unknown errors don't leak to the user
+func ErrorOf(err error) Response {
+	msg, ok := errkind.Message(err)
+	if !ok {
+		return Error("internal error")
+	}
+
+	return Error(msg)
+}
+
 func ValidationErrors(errs validator.ValidationErrors) Response {
 	var errMsgs []string
 
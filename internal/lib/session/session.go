@@ -0,0 +1,83 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rigbyel/ad-market/internal/lib/jwt"
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+	"github.com/rigbyel/ad-market/internal/lib/refresh"
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+const userAgentMaxLen = 256
+
+// New creates a session for the user logging in with the given request
+func New(userID int64, r *http.Request) (*models.Session, error) {
+	const op = "lib.session.New"
+
+	id, err := opaque.New()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > userAgentMaxLen {
+		userAgent = userAgent[:userAgentMaxLen]
+	}
+
+	now := time.Now()
+
+	return &models.Session{
+		Id:         id,
+		UserId:     userID,
+		UserAgent:  userAgent,
+		IP:         request.ClientIP(r),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}, nil
+}
+
+type Creator interface {
+	CreateSession(session *models.Session, rt *models.RefreshToken) error
+}
+
+// Tokens issued to the client when a session starts
+type Tokens struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+}
+
+// Start creates a session for the user logging in with the given request
+// and issues access and refresh tokens bound to it
+func Start(creator Creator, user models.User, r *http.Request, tokens *jwt.Manager, tokenTL, refreshTL time.Duration) (*Tokens, error) {
+	const op = "lib.session.Start"
+
+	session, err := New(user.Id, r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	refreshToken, rt, err := refresh.New(user.Id, session.Id, refreshTL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	accessToken, err := tokens.NewToken(user, session.Id, tokenTL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := creator.CreateSession(session, rt); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Tokens{
+		SessionID:    session.Id,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rigbyel/ad-market/internal/lib/opaque"
+)
+
+// parameters of RFC 6238 codes supported by common authenticator apps
+const (
+	Period = 30 * time.Second
+	Digits = 6
+
+	// number of steps back and forth accepted to tolerate clock drift
+	Skew = 1
+
+	secretSize = 20
+
+	// recovery code is 80 random bits written as four groups of base32 characters
+	RecoveryCodesCount = 10
+	recoveryCodeSize   = 10
+	recoveryGroupLen   = 4
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret generates random base32 encoded secret
+func NewSecret() (string, error) {
+	const op = "lib.totp.NewSecret"
+
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// URI builds otpauth URI to be shown as QR code for authenticator app
+func URI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: q.Encode(),
+	}
+
+	return u.String()
+}
+
+// Step returns number of the time step t belongs to
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code calculates code of the given time step
+func Code(secret string, step int64) (string, error) {
+	const op = "lib.totp.Code"
+
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Validate checks code against steps around now, skew steps back and forth are accepted
+// to tolerate clock drift. Matched step is returned, so that the caller can reject its reuse
+func Validate(secret, code string, now time.Time, skew int) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	current := Step(now)
+
+	for i := -skew; i <= skew; i++ {
+		step := current + int64(i)
+
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// NewRecoveryCodes generates single-use codes for logging in without authenticator app
+// codes are returned along with their hashes to keep in storage
+func NewRecoveryCodes() (codes, hashes []string, err error) {
+	const op = "lib.totp.NewRecoveryCodes"
+
+	for i := 0; i < RecoveryCodesCount; i++ {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		s := strings.ToLower(encoding.EncodeToString(b))
+
+		groups := make([]string, 0, len(s)/recoveryGroupLen)
+		for j := 0; j < len(s); j += recoveryGroupLen {
+			groups = append(groups, s[j:j+recoveryGroupLen])
+		}
+
+		code := strings.Join(groups, "-")
+
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode returns hash of recovery code ignoring case, spaces and dashes
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+
+	return opaque.Hash(code)
+}
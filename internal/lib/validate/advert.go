@@ -1,97 +1,153 @@
 package validate
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	_ "image/jpeg"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/models"
 	"github.com/rigbyel/ad-market/internal/models/constraints"
 )
 
+const (
+	imageFetchTimeout = 5 * time.Second
+	imageMaxBytes     = 1 << 20
+)
+
+var errForbiddenAddress = errors.New("address is not allowed")
+
+// imageClient fetches user supplied images, it gives up quickly and doesn't connect to
+// loopback, private and link-local addresses, so that users can't make the server reach internal hosts
+var imageClient = &http.Client{
+	Timeout: imageFetchTimeout,
+	Transport: &http.Transport{
+		// addresses are checked after resolving, so DNS names and redirects can't bypass the check
+		DialContext: (&net.Dialer{
+			Timeout: imageFetchTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+
+				ip := net.ParseIP(host)
+				if ip == nil || !publicIP(ip) {
+					return errForbiddenAddress
+				}
+
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   imageFetchTimeout,
+		ResponseHeaderTimeout: imageFetchTimeout,
+	},
+}
+
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast()
+}
+
 // validates advert according to constraints from models/constraints
-func ValidateAdvert(ad request.AdvertRequest) []string {
-	errs := []string{}
+func ValidateAdvert(ad request.AdvertRequest) Errors {
+	errs := Errors{}
 
 	// check if advert header exists
 	if len(ad.Header) == 0 {
-		errs = append(errs, "header is required")
+		errs.add("header", CodeRequired, "header is required")
 	}
 
 	// check advert header length
-	if len(ad.Header) > constraints.AdvertHeaderMaxLen {
-		errs = append(errs, "advert header is too long")
+	if TextLength(ad.Header) > constraints.AdvertHeaderMaxLen {
+		errs.add("header", CodeTooLong, "advert header is too long")
 	}
 
 	// check advert body length
-	if len(ad.Body) > constraints.AdvertBodyMaxLen {
-		errs = append(errs, "advert body is too long")
+	if TextLength(ad.Body) > constraints.AdvertBodyMaxLen {
+		errs.add("body", CodeTooLong, "advert body is too long")
 	}
 
 	// check if advert has price
 	if ad.Price == 0 {
-		errs = append(errs, "price is required")
+		errs.add("price", CodeRequired, "price is required")
 	}
 
 	// check if price in permmitted range
 	if ad.Price > constraints.MaxPrice {
-		errs = append(errs, "price is to big")
+		errs.add("price", CodeTooLarge, "price is to big")
 	}
 
 	if ad.Price < constraints.MinPrice {
-		errs = append(errs, "price is too small")
+		errs.add("price", CodeTooSmall, "price is too small")
 	}
 
-	// validate image
-	err := validateImage(ad.ImageURL)
-	if err != nil {
-		errs = append(errs, err.Error())
+	// check if advert is created as published or as a draft
+	if ad.Status != "" && ad.Status != models.AdvertStatusActive && ad.Status != models.AdvertStatusDraft {
+		errs.add("status", CodeInvalid, "advert status should be either active or draft")
 	}
 
+	// validate image
+	errs = append(errs, validateImage("image_url", ad.ImageURL)...)
+
 	return errs
 }
 
 // validates image according to size and extention constraints
-func validateImage(imgURL string) error {
+func validateImage(field, imgURL string) Errors {
 	if imgURL == "" {
 		return nil
 	}
 
+	// only web images can be fetched
+	u, err := url.Parse(imgURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalid(field, CodeImageURL, "invalid image url")
+	}
+
 	// get response from image url
-	resp, err := http.Get(imgURL)
+	resp, err := imageClient.Get(imgURL)
 	if err != nil {
-		return fmt.Errorf("invalid image url")
+		return invalid(field, CodeImageURL, "invalid image url")
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return invalid(field, CodeImageURL, "invalid image url")
+	}
+
 	// check if image extention is valid
 	ext := filepath.Ext(imgURL)
 	if _, ok := constraints.ImageExtentions[ext]; !ok {
-		return fmt.Errorf("wrong image extention: %s", ext)
+		return invalid(field, CodeImageExt, fmt.Sprintf("wrong image extention: %s", ext))
 	}
 
-	// decode image
-	m, _, err := image.Decode(resp.Body)
+	// decode image header, size is all we need
+	cfg, _, err := image.DecodeConfig(io.LimitReader(resp.Body, imageMaxBytes))
 	if err != nil {
-		return fmt.Errorf("invalid image url %w", err)
+		return invalid(field, CodeImage, fmt.Sprintf("invalid image url %s", err))
 	}
 
-	// get image bounds
-	g := m.Bounds()
-
 	// get height and width
-	height := g.Dy()
-	width := g.Dx()
+	height := cfg.Height
+	width := cfg.Width
 
 	// check if image size is valid
 	if height > constraints.ImageMaxHeight || width > constraints.ImageMaxWidth {
-		return fmt.Errorf("image is too big")
+		return invalid(field, CodeTooLarge, "image is too big")
 	}
 
 	if height < constraints.ImageMinHeight || width < constraints.ImageMinWidth {
-		return fmt.Errorf("image is too small")
+		return invalid(field, CodeTooSmall, "image is too small")
 	}
 
 	return nil
@@ -0,0 +1,27 @@
+package validate
+
+import (
+	"net/mail"
+	"strings"
+
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+)
+
+// validates email address, it should be a bare address without display name
+func ValidateEmail(email string) Errors {
+	if len(email) > constraints.EmailMaxLen {
+		return invalid("email", CodeTooLong, "email is too long")
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return invalid("email", CodeInvalid, "invalid email")
+	}
+
+	return nil
+}
+
+// NormalizeEmail trims spaces and lowers case of email, so that one address can't be used twice
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,70 @@
+package validate
+
+import (
+	"strings"
+
+	"github.com/rigbyel/ad-market/internal/lib/errkind"
+)
+
+// codes of broken rules, clients use them to pick messages and highlight fields
+const (
+	CodeRequired     = "required"
+	CodeTooShort     = "too_short"
+	CodeTooLong      = "too_long"
+	CodeTooSmall     = "too_small"
+	CodeTooLarge     = "too_large"
+	CodeInvalid      = "invalid"
+	CodeCharacters   = "invalid_characters"
+	CodeMixedScripts = "mixed_scripts"
+	CodeNoUpper      = "missing_uppercase"
+	CodeNoLower      = "missing_lowercase"
+	CodeNoDigit      = "missing_digit"
+	CodeNoSymbol     = "missing_symbol"
+	CodeCommon       = "too_common"
+	CodeSimilar      = "similar_to_login"
+	CodeImageURL     = "invalid_url"
+	CodeImageExt     = "invalid_extension"
+	CodeImage        = "invalid_image"
+)
+
+// FieldError is a broken rule of a field of user input
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors are broken rules of user input
+type Errors []FieldError
+
+// invalid creates errors with one broken rule
+func invalid(field, code, msg string) Errors {
+	return Errors{{Field: field, Code: code, Message: msg}}
+}
+
+func (e *Errors) add(field, code, msg string) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: msg})
+}
+
+// OnField moves errors to another field, e.g. password rules to new_password
+func (e Errors) OnField(field string) Errors {
+	for i := range e {
+		e[i].Field = field
+	}
+
+	return e
+}
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Message)
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+// Is makes errors of user input errors of kind errkind.Invalid
+func (e Errors) Is(target error) bool {
+	return target == errkind.Invalid
+}
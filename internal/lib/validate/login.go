@@ -1,33 +1,81 @@
 package validate
 
 import (
-	"regexp"
+	"strings"
+	"unicode"
 
 	"github.com/rigbyel/ad-market/internal/models/constraints"
 )
 
-// validates user login
-func ValidateLogin(login string) []string {
+// cyrillic letters looking like latin ones, logins differing only in them are treated as the same login
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i', 'ї': 'i', 'ј': 'j', 'ԁ': 'd',
+	'һ': 'h', 'ӏ': 'l', 'ԛ': 'q', 'ԝ': 'w', 'ь': 'b',
+}
+
+// LoginPolicy is a set of rules for logins
+type LoginPolicy struct {
+	// allow logins written in cyrillic letters in addition to latin ones
+	AllowCyrillic bool
+}
+
+// Validate checks user login, it should be normalized with NormalizeLogin
+func (p *LoginPolicy) Validate(login string) Errors {
 	if login == "" {
-		return []string{"login is required"}
+		return invalid("login", CodeRequired, "login is required")
 	}
 
-	errs := []string{}
+	errs := Errors{}
 
 	// check if login has a valid size
-	if len(login) < constraints.LoginMinLen {
-		errs = append(errs, "login is too short")
+	if TextLength(login) < constraints.LoginMinLen {
+		errs.add("login", CodeTooShort, "login is too short")
+	}
+
+	if TextLength(login) > constraints.LoginMaxLen {
+		errs.add("login", CodeTooLong, "login is too long")
 	}
 
-	if len(login) > constraints.LoginMaxLen {
-		errs = append(errs, "login is too long")
+	// check if login consists only from alphanumeric characters of allowed alphabets
+	var latin, cyrillic, other bool
+	for _, r := range login {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			latin = latin || unicode.IsLetter(r)
+		case p.AllowCyrillic && unicode.Is(unicode.Cyrillic, r) && unicode.IsLetter(r):
+			cyrillic = true
+		default:
+			other = true
+		}
 	}
 
-	// check if login consists only from alphanumeric characters
-	alphanumeric := regexp.MustCompile(`^[a-zA-Z0-9]*$`).MatchString(login)
-	if !alphanumeric {
-		errs = append(errs, "login should contain only alphanumeric characters")
+	if other {
+		if p.AllowCyrillic {
+			errs.add("login", CodeCharacters, "login should contain only latin or cyrillic letters and digits")
+		} else {
+			errs.add("login", CodeCharacters, "login should contain only alphanumeric characters")
+		}
+	}
+
+	// letters of both alphabets make it possible to imitate another user's login
+	if latin && cyrillic {
+		errs.add("login", CodeMixedScripts, "login should not mix latin and cyrillic letters")
 	}
 
 	return errs
 }
+
+// LoginKey folds login for uniqueness checks
+// logins with the same key differ only in case or in look-alike cyrillic and latin letters
+func LoginKey(login string) string {
+	login = strings.ToLower(NormalizeLogin(login))
+
+	return strings.Map(func(r rune) rune {
+		if latin, ok := confusables[r]; ok {
+			return latin
+		}
+
+		return r
+	}, login)
+}
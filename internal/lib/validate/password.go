@@ -1,41 +1,181 @@
 package validate
 
 import (
-	"regexp"
+	"bufio"
+	"fmt"
+	"os"
 	"strings"
-
-	"github.com/rigbyel/ad-market/internal/models/constraints"
+	"unicode"
+	"unicode/utf8"
 )
 
-// validates user's password
-func ValidatePassword(pwd string) []string {
+// logins shorter than that are too common to be looked for inside passwords
+const loginCoreMinLen = 4
+
+// PasswordPolicy is a set of rules for new passwords
+type PasswordPolicy struct {
+	// minimal length in characters
+	MinLen int
+
+	// maximal length in bytes, bcrypt doesn't accept passwords longer than 72 bytes
+	MaxLen int
+
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// reject passwords made of user's login
+	CheckLogin bool
+
+	// common and breached passwords in lower case
+	blocklist map[string]struct{}
+}
+
+// LoadBlocklist reads passwords that can't be used from file with one password per line
+// empty lines and lines starting with # are skipped, passwords are compared ignoring case
+func (p *PasswordPolicy) LoadBlocklist(path string) error {
+	const op = "lib.validate.LoadBlocklist"
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	blocklist := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		blocklist[strings.ToLower(line)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	p.blocklist = blocklist
+
+	return nil
+}
+
+// Validate checks new password of the user with the given login
+// errors are reported on field password, see Errors.OnField
+func (p *PasswordPolicy) Validate(pwd, login string) Errors {
 	if pwd == "" {
-		return []string{"password is required"}
+		return invalid("password", CodeRequired, "password is required")
+	}
+
+	errs := Errors{}
+
+	if utf8.RuneCountInString(pwd) < p.MinLen {
+		errs.add("password", CodeTooShort, fmt.Sprintf("password should contain at least %d characters", p.MinLen))
+	}
+
+	if p.MaxLen > 0 && len(pwd) > p.MaxLen {
+		errs.add("password", CodeTooLong, fmt.Sprintf("password should be at most %d bytes long", p.MaxLen))
 	}
 
-	errs := []string{}
+	var upper, lower, digit, symbol bool
+	for _, r := range pwd {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if p.RequireUpper && !upper {
+		errs.add("password", CodeNoUpper, "password should contain at least one uppercase letter")
+	}
 
-	if len(pwd) < constraints.PasswordMinLen {
-		errs = append(errs, "password should contain at least 8 characters")
+	if p.RequireLower && !lower {
+		errs.add("password", CodeNoLower, "password should contain at least one lowercase letter")
 	}
 
-	if pwd == strings.ToLower(pwd) {
-		errs = append(errs, "password should contain at least one uppercase letter")
+	if p.RequireDigit && !digit {
+		errs.add("password", CodeNoDigit, "password should contain at least one digit")
 	}
 
-	if pwd == strings.ToUpper(pwd) {
-		errs = append(errs, "password should contain at least one lowercase letter")
+	if p.RequireSymbol && !symbol {
+		errs.add("password", CodeNoSymbol, "password should contain at least one special character")
 	}
 
-	numeric := regexp.MustCompile(`\d`).MatchString(pwd)
-	if !numeric {
-		errs = append(errs, "password should contain at least one digit")
+	if _, ok := p.blocklist[strings.ToLower(pwd)]; ok {
+		errs.add("password", CodeCommon, "password is too common")
 	}
 
-	alphabetic := regexp.MustCompile(`[A-Za-z_]`).MatchString(pwd)
-	if !alphabetic {
-		errs = append(errs, "password should contain at least one latin letter")
+	if p.CheckLogin && login != "" && similar(pwd, login) {
+		errs.add("password", CodeSimilar, "password should not be similar to login")
 	}
 
 	return errs
 }
+
+// reports whether password is login with small changes: in other case, reversed,
+// with a few characters changed or login's letters surrounded by digits and symbols
+func similar(pwd, login string) bool {
+	pwd = strings.ToLower(pwd)
+	login = strings.ToLower(login)
+
+	if strings.Contains(pwd, login) || strings.Contains(pwd, reverse(login)) {
+		return true
+	}
+
+	// alice1 in Alice2024! is found by letters of the login
+	core := strings.TrimFunc(login, func(r rune) bool { return !unicode.IsLetter(r) })
+	if utf8.RuneCountInString(core) >= loginCoreMinLen &&
+		(strings.Contains(pwd, core) || strings.Contains(pwd, reverse(core))) {
+		return true
+	}
+
+	return distance(pwd, login) <= 2
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return string(runes)
+}
+
+// Levenshtein distance between strings
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,28 @@
+package validate
+
+import (
+	"github.com/rigbyel/ad-market/internal/lib/request"
+	"github.com/rigbyel/ad-market/internal/models/constraints"
+)
+
+// validates changes of user's public profile
+func ValidateProfile(p request.ProfileRequest) Errors {
+	errs := Errors{}
+
+	// check bio length
+	if p.Bio != nil && TextLength(*p.Bio) > constraints.BioMaxLen {
+		errs.add("bio", CodeTooLong, "bio is too long")
+	}
+
+	// validate avatar image
+	if p.AvatarURL != nil {
+		errs = append(errs, validateImage("avatar_url", *p.AvatarURL)...)
+	}
+
+	// validate email unless it's being removed
+	if p.Email != nil && *p.Email != "" {
+		errs = append(errs, ValidateEmail(*p.Email)...)
+	}
+
+	return errs
+}
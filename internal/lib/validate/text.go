@@ -0,0 +1,55 @@
+package validate
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	zeroWidthJoiner = '\u200d'
+
+	variationSelectorFirst = '\ufe00'
+	variationSelectorLast  = '\ufe0f'
+
+	emojiModifierFirst = '\U0001f3fb'
+	emojiModifierLast  = '\U0001f3ff'
+)
+
+// NormalizeText brings user's text to Unicode NFKC form, so that visually equal strings
+// are stored equally and compatibility characters like fullwidth letters become ordinary ones
+func NormalizeText(s string) string {
+	return norm.NFKC.String(s)
+}
+
+// TextLength counts characters as the user sees them rather than bytes
+// combining marks, variation selectors, emoji modifiers and characters joined by
+// zero width joiner don't count as separate characters
+func TextLength(s string) int {
+	count := 0
+	joined := false
+
+	for _, r := range s {
+		switch {
+		case r == zeroWidthJoiner:
+			joined = true
+			continue
+		case unicode.In(r, unicode.Mn, unicode.Me):
+		case r >= variationSelectorFirst && r <= variationSelectorLast:
+		case r >= emojiModifierFirst && r <= emojiModifierLast:
+		case joined:
+		default:
+			count++
+		}
+
+		joined = false
+	}
+
+	return count
+}
+
+// NormalizeLogin prepares login entered by the user for validation and lookup
+func NormalizeLogin(login string) string {
+	return strings.TrimSpace(NormalizeText(login))
+}
@@ -0,0 +1,18 @@
+package models
+
+// UserData is everything stored about the user, it's given to them on request
+type UserData struct {
+	User             User
+	TwoFactorEnabled bool
+	Adverts          []Advert
+
+	// reports the user filed against adverts
+	Reports    []Report
+	Bans       []Ban
+	Sessions   []Session
+	APIKeys    []APIKey
+	Identities []Identity
+
+	// entries where the user is the actor or the target
+	AuditLog []AuditEntry
+}
@@ -4,6 +4,14 @@ import (
 	"time"
 )
 
+const (
+	AdvertStatusActive   = "active"
+	AdvertStatusDraft    = "draft"
+	AdvertStatusArchived = "archived"
+	AdvertStatusHidden   = "hidden"
+	AdvertStatusRejected = "rejected"
+)
+
 type Advert struct {
 	Id          int64
 	Header      string
@@ -12,4 +20,8 @@ type Advert struct {
 	Price       int
 	Date        time.Time
 	AuthorLogin string
+	Status      string
+
+	// reason of rejection given by moderator, visible to the author
+	ModerationReason string
 }
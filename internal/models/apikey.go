@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// APIKey is a stored key for programmatic access on behalf of the user
+// the key itself is shown once on creation, only its hash and prefix are kept
+type APIKey struct {
+	Id     int64
+	UserId int64
+	Name   string
+
+	// public part of the key shown to the user to tell keys apart
+	Prefix  string
+	KeyHash string
+	Scopes  []string
+
+	CreatedAt time.Time
+
+	// zero ExpiresAt means the key doesn't expire
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	RevokedAt  time.Time
+}
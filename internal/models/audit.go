@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+type AuditEvent string
+
+const (
+	AuditLoginSuccess AuditEvent = "login.success"
+	AuditLoginFailure AuditEvent = "login.failure"
+	AuditRegister     AuditEvent = "user.register"
+	AuditUserDelete   AuditEvent = "user.delete"
+	AuditUserExport   AuditEvent = "user.export"
+
+	AuditPasswordChange       AuditEvent = "user.password-change"
+	AuditPasswordResetRequest AuditEvent = "user.password-reset-request"
+	AuditPasswordReset        AuditEvent = "user.password-reset"
+
+	AuditEmailChange AuditEvent = "user.email-change"
+	AuditEmailVerify AuditEvent = "user.email-verify"
+
+	AuditTwoFactorEnable  AuditEvent = "user.2fa-enable"
+	AuditTwoFactorDisable AuditEvent = "user.2fa-disable"
+
+	AuditAPIKeyCreate AuditEvent = "api-key.create"
+	AuditAPIKeyRevoke AuditEvent = "api-key.revoke"
+
+	AuditIdentityLink   AuditEvent = "user.identity-link"
+	AuditIdentityUnlink AuditEvent = "user.identity-unlink"
+
+	AuditAdvertCreate AuditEvent = "advert.create"
+	AuditAdvertHide   AuditEvent = "advert.hide"
+	AuditAdvertReport AuditEvent = "advert.report"
+
+	AuditModerationApprove   AuditEvent = "moderation.approve"
+	AuditModerationReject    AuditEvent = "moderation.reject"
+	AuditModerationBanAuthor AuditEvent = "moderation.ban-author"
+
+	AuditUserBan    AuditEvent = "user.ban"
+	AuditUserUnban  AuditEvent = "user.unban"
+	AuditUserAppeal AuditEvent = "user.appeal"
+	AuditRoleChange AuditEvent = "user.role-change"
+)
+
+// AuditEntry is a record of security or moderation event
+// actor is empty for anonymous requests
+type AuditEntry struct {
+	Id         int64
+	Event      AuditEvent
+	ActorId    int64
+	ActorLogin string
+	Target     string
+	Details    string
+	IP         string
+	RequestId  string
+	CreatedAt  time.Time
+}
+
+// AuditFilter selects audit entries, empty fields match everything
+type AuditFilter struct {
+	Event      AuditEvent
+	ActorLogin string
+	Target     string
+	IP         string
+	RequestId  string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
@@ -1,7 +1,8 @@
 package constraints
 
 const (
-	AdvertsOnPage = 10
+	AdvertsOnPage      = 10
+	AuditEntriesOnPage = 50
 
 	AdvertHeaderMaxLen = 100
 	AdvertBodyMaxLen   = 600
@@ -13,9 +14,18 @@ const (
 	ImageMaxHeight = 720
 	ImageMinHeight = 60
 
-	LoginMinLen    = 5
-	LoginMaxLen    = 20
-	PasswordMinLen = 8
+	LoginMinLen = 5
+	LoginMaxLen = 20
+
+	BioMaxLen   = 500
+	EmailMaxLen = 254
+
+	ReportCommentMaxLen    = 500
+	ModerationReasonMaxLen = 500
+	AppealMaxLen           = 1000
+
+	APIKeyNameMaxLen = 100
+	APIKeysMaxCount  = 20
 )
 
 var ImageExtentions = map[string]bool{
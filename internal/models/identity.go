@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Identity links the user to their account at external OpenID Connect provider
+type Identity struct {
+	Id       int64
+	UserId   int64
+	Provider string
+
+	// identifier of the user at the provider
+	Subject string
+
+	// email reported by the provider when the identity was linked
+	Email     string
+	CreatedAt time.Time
+}
+
+// OIDCState is a stored single-use authorization request sent to OpenID Connect provider
+// it's found by the state returned to callback and keeps the secrets to finish the flow
+type OIDCState struct {
+	Id        int64
+	StateHash string
+	Provider  string
+	Nonce     string
+
+	// PKCE code verifier
+	Verifier string
+
+	// user linking the identity to their account, zero for login
+	UserId int64
+
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+type ReportReason string
+
+const (
+	ReportReasonSpam       ReportReason = "spam"
+	ReportReasonScam       ReportReason = "scam"
+	ReportReasonProhibited ReportReason = "prohibited"
+	ReportReasonOffensive  ReportReason = "offensive"
+	ReportReasonOther      ReportReason = "other"
+)
+
+var ReportReasons = map[ReportReason]bool{
+	ReportReasonSpam:       true,
+	ReportReasonScam:       true,
+	ReportReasonProhibited: true,
+	ReportReasonOffensive:  true,
+	ReportReasonOther:      true,
+}
+
+// Report is a complaint of a user about an advert
+type Report struct {
+	Id         int64
+	AdvertId   int64
+	ReporterId int64
+	Reason     ReportReason
+	Comment    string
+	CreatedAt  time.Time
+	ResolvedAt time.Time
+}
+
+// ModerationItem is a reported advert waiting for moderator's decision
+type ModerationItem struct {
+	Advert       Advert
+	ReportsCount int
+	Reasons      []ReportReason
+}
+
+// Ban prevents user from logging in
+// ban without expiration time is permanent
+type Ban struct {
+	Id        int64
+	UserId    int64
+	Reason    string
+	CreatedAt time.Time
+	CreatedBy int64
+	ExpiresAt time.Time
+	LiftedAt  time.Time
+
+	// banned user's explanation why the ban should be lifted
+	Appeal     string
+	AppealedAt time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Session is created on every login and lives until its refresh tokens expire or it's revoked
+type Session struct {
+	Id         string
+	UserId     int64
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  time.Time
+}
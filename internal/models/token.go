@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// RefreshToken is a stored refresh token
+// tokens issued by rotating each other belong to one session
+type RefreshToken struct {
+	Id        int64
+	UserId    int64
+	SessionId string
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	RevokedAt time.Time
+}
+
+// PasswordReset is a stored single-use token for resetting forgotten password
+type PasswordReset struct {
+	Id        int64
+	UserId    int64
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// TOTP is authenticator app secret of the user
+// two-factor authentication is enabled once the user confirms it with a code, zero EnabledAt means it's pending
+type TOTP struct {
+	UserId    int64
+	Secret    string
+	CreatedAt time.Time
+	EnabledAt time.Time
+
+	// time step of the last accepted code, codes of this and earlier steps are rejected
+	LastStep int64
+}
+
+// MFAChallenge is a stored single-use token of login waiting for the second factor
+type MFAChallenge struct {
+	Id        int64
+	UserId    int64
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	Attempts  int
+}
@@ -1,7 +1,29 @@
 package models
 
+import "time"
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
 type User struct {
-	Id       int64
-	Login    string
-	PassHash []byte
+	Id    int64
+	Login string
+
+	// login folded for uniqueness checks, see validate.LoginKey
+	LoginKey  string
+	PassHash  []byte
+	RegDate   time.Time
+	Bio       string
+	AvatarURL string
+	Rating    float64
+	Role      Role
+
+	// optional email, zero EmailVerifiedAt means it isn't verified
+	Email           string
+	EmailVerifiedAt time.Time
 }
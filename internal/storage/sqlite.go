@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 	"github.com/rigbyel/ad-market/internal/models"
@@ -13,11 +15,28 @@ type Storage struct {
 	db *sql.DB
 }
 
+// executor is implemented by both sql.DB and sql.Tx
+type executor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// scanner is implemented by both sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
 // creates new instance of Storage
 func New(storagePath string) (*Storage, error) {
 	const op = "storage.sqlite.New"
 
-	db, err := sql.Open("sqlite3", storagePath)
+	// sqlite doesn't enforce foreign keys by default, so ON DELETE CASCADE never fires without this
+	// it's set in connection string, because the pragma applies only to the connection it's run on
+	dsn := storagePath + "?_foreign_keys=on"
+	if strings.Contains(storagePath, "?") {
+		dsn = storagePath + "&_foreign_keys=on"
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return &Storage{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -40,15 +59,23 @@ func (s *Storage) SaveUser(u *models.User) (*models.User, error) {
 
 	// prepare query
 	stmt, err := s.db.Prepare(
-		"INSERT INTO users (login, passHash) VALUES ($1, $2)",
+		"INSERT INTO users (login, loginKey, passHash, regDate, role, email) VALUES ($1, $2, $3, $4, $5, $6)",
 	)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if u.Role == "" {
+		u.Role = models.RoleUser
+	}
+
 	// execute query
-	res, err := stmt.Exec(u.Login, u.PassHash)
+	res, err := stmt.Exec(u.Login, nullString(u.LoginKey), u.PassHash, u.RegDate, u.Role, nullString(u.Email))
 	if err != nil {
+		if isEmailTaken(err) {
+			return nil, fmt.Errorf("%s: %w", op, ErrEmailExists)
+		}
+
 		var sqliteErr sqlite3.Error
 
 		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
@@ -73,14 +100,14 @@ func (s *Storage) SaveUser(u *models.User) (*models.User, error) {
 func (s *Storage) User(login string) (*models.User, error) {
 	const op = "storage.sqlite.User"
 
-	// get user's passhash from database
+	// get user from database
 	row := s.db.QueryRow(
-		"SELECT passHash FROM users WHERE login = $1",
+		"SELECT "+userColumns+" FROM users WHERE login = $1",
 		login,
 	)
 
-	var passHash []byte
-	if err := row.Scan(&passHash); err != nil {
+	user, err := scanUser(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
@@ -88,14 +115,21 @@ func (s *Storage) User(login string) (*models.User, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// get user id from database
-	row = s.db.QueryRow(
-		"SELECT id FROM users WHERE login = $1",
-		login,
+	return user, nil
+}
+
+// gets user whose login has the given key, i.e. differs from the requested one
+// only in case or look-alike letters
+func (s *Storage) UserByLoginKey(key string) (*models.User, error) {
+	const op = "storage.sqlite.UserByLoginKey"
+
+	row := s.db.QueryRow(
+		"SELECT "+userColumns+" FROM users WHERE loginKey = $1",
+		key,
 	)
 
-	var id int64
-	if err := row.Scan(&id); err != nil {
+	user, err := scanUser(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
@@ -103,24 +137,110 @@ func (s *Storage) User(login string) (*models.User, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// create models.User struct with the given login, id and passHash
-	user := &models.User{
-		Id:       id,
-		Login:    login,
-		PassHash: passHash,
+	return user, nil
+}
+
+// gets user with the given id from storage
+func (s *Storage) UserByID(id int64) (*models.User, error) {
+	const op = "storage.sqlite.UserByID"
+
+	// get user from database
+	row := s.db.QueryRow(
+		"SELECT "+userColumns+" FROM users WHERE id = $1",
+		id,
+	)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return user, nil
 }
 
+// columns of users table in the order expected by scanUser
+const userColumns = "id, login, loginKey, passHash, regDate, bio, avatarURL, rating, role, email, emailVerifiedAt"
+
+// scans user from query result
+func scanUser(row scanner) (*models.User, error) {
+	var user models.User
+	var regDate, emailVerifiedAt sql.NullTime
+	var email, loginKey sql.NullString
+
+	err := row.Scan(
+		&user.Id, &user.Login, &loginKey, &user.PassHash, &regDate, &user.Bio, &user.AvatarURL, &user.Rating, &user.Role,
+		&email, &emailVerifiedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user.LoginKey = loginKey.String
+	user.Email = email.String
+	user.EmailVerifiedAt = emailVerifiedAt.Time
+
+	// users registered before profiles were introduced have no registration date
+	if regDate.Valid {
+		user.RegDate = regDate.Time
+	}
+
+	return &user, nil
+}
+
+// changes role of the user with the given login
+func (s *Storage) SetUserRole(login string, role models.Role) error {
+	const op = "storage.sqlite.SetUserRole"
+
+	res, err := s.db.Exec(
+		"UPDATE users SET role = $1 WHERE login = $2",
+		role,
+		login,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// updates public profile fields of the user
+func (s *Storage) UpdateProfile(u *models.User) error {
+	const op = "storage.sqlite.UpdateProfile"
+
+	_, err := s.db.Exec(
+		"UPDATE users SET bio = $1, avatarURL = $2 WHERE id = $3",
+		u.Bio,
+		u.AvatarURL,
+		u.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 // saves advert in the storage
 func (s *Storage) SaveAd(ad *models.Advert) (*models.Advert, error) {
 	const op = "storage.sqlite.SaveAd"
 
 	// prepare query
 	stmt, err := s.db.Prepare(
-		`INSERT INTO adverts (header, body, imageURL, price, date, authorLogin)
-		VAlUES ($1, $2, $3, $4, $5, $6)`,
+		`INSERT INTO adverts (header, body, imageURL, price, date, authorLogin, status)
+		VAlUES ($1, $2, $3, $4, $5, $6, $7)`,
 	)
 
 	if err != nil {
@@ -128,7 +248,7 @@ func (s *Storage) SaveAd(ad *models.Advert) (*models.Advert, error) {
 	}
 
 	// execute query
-	res, err := stmt.Exec(ad.Header, ad.Body, ad.ImageURL, ad.Price, ad.Date, ad.AuthorLogin)
+	res, err := stmt.Exec(ad.Header, ad.Body, ad.ImageURL, ad.Price, ad.Date, ad.AuthorLogin, ad.Status)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -144,37 +264,183 @@ func (s *Storage) SaveAd(ad *models.Advert) (*models.Advert, error) {
 	return ad, nil
 }
 
-// gets all adverts within given price range from storage
+// gets advert with the given id from storage
+func (s *Storage) Advert(id int64) (*models.Advert, error) {
+	const op = "storage.sqlite.Advert"
+
+	rows, err := s.db.Query(
+		"SELECT "+advertColumns+" FROM adverts WHERE id = $1",
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	adverts, err := scanAdverts(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(*adverts) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrAdvertNotFound)
+	}
+
+	return &(*adverts)[0], nil
+}
+
+// changes status of the advert with the given id
+func (s *Storage) SetAdvertStatus(id int64, status string) error {
+	const op = "storage.sqlite.SetAdvertStatus"
+
+	res, err := s.db.Exec(
+		"UPDATE adverts SET status = $1 WHERE id = $2",
+		status,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrAdvertNotFound)
+	}
+
+	return nil
+}
+
+// gets all active adverts within given price range from storage
 func (s *Storage) Adverts(minPrice, maxPrice int) (*[]models.Advert, error) {
 	const op = "storage.sqlite.Adverts"
 
-	var adverts []models.Advert
-
 	// get adverts from database
 	rows, err := s.db.Query(
-		"SELECT * FROM adverts WHERE price >= $1 AND price <= $2",
+		"SELECT "+advertColumns+" FROM adverts WHERE price >= $1 AND price <= $2 AND status = $3 AND "+authorNotBanned("$4"),
 		minPrice,
 		maxPrice,
+		models.AdvertStatusActive,
+		time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	adverts, err := scanAdverts(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return adverts, nil
+}
+
+// gets all active adverts of the user with the given login from storage
+func (s *Storage) AdvertsByAuthor(login string) (*[]models.Advert, error) {
+	const op = "storage.sqlite.AdvertsByAuthor"
+
+	// get adverts from database
+	rows, err := s.db.Query(
+		"SELECT "+advertColumns+" FROM adverts WHERE authorLogin = $1 AND status = $2 AND "+authorNotBanned("$3"),
+		login,
+		models.AdvertStatusActive,
+		time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	adverts, err := scanAdverts(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return adverts, nil
+}
+
+// gets adverts of the user with the given login in any status, including drafts and archived ones
+func (s *Storage) UserAdverts(login string) (*[]models.Advert, error) {
+	const op = "storage.sqlite.UserAdverts"
+
+	// get adverts from database
+	rows, err := s.db.Query(
+		"SELECT "+advertColumns+" FROM adverts WHERE authorLogin = $1",
+		login,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+
+	adverts, err := scanAdverts(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return adverts, nil
+}
+
+// counts active adverts of the user with the given login
+func (s *Storage) ActiveAdvertsCount(login string) (int, error) {
+	const op = "storage.sqlite.ActiveAdvertsCount"
+
+	row := s.db.QueryRow(
+		"SELECT COUNT(*) FROM adverts WHERE authorLogin = $1 AND status = $2 AND "+authorNotBanned("$3"),
+		login,
+		models.AdvertStatusActive,
+		time.Now(),
+	)
+
+	var count int
+	if err := row.Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// builds condition excluding adverts of banned authors
+// now is the placeholder for current time
+func authorNotBanned(now string) string {
+	return `NOT EXISTS (
+		SELECT 1 FROM bans JOIN users ON users.id = bans.userId
+		WHERE users.login = adverts.authorLogin AND bans.liftedAt IS NULL
+		AND (bans.expiresAt IS NULL OR bans.expiresAt > ` + now + `))`
+}
+
+// columns of adverts table in the order expected by scanAdverts
+const advertColumns = "id, header, body, imageURL, price, date, authorLogin, status, moderationReason"
+
+// scans adverts from query result and closes it
+func scanAdverts(rows *sql.Rows) (*[]models.Advert, error) {
 	defer rows.Close()
 
+	var adverts []models.Advert
+
 	for rows.Next() {
 		var ad models.Advert
 
-		err = rows.Scan(&ad.Id, &ad.Header, &ad.Body, &ad.ImageURL, &ad.Price, &ad.Date, &ad.AuthorLogin)
+		err := rows.Scan(
+			&ad.Id,
+			&ad.Header,
+			&ad.Body,
+			&ad.ImageURL,
+			&ad.Price,
+			&ad.Date,
+			&ad.AuthorLogin,
+			&ad.Status,
+			&ad.ModerationReason,
+		)
 		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return nil, err
 		}
 
 		adverts = append(adverts, ad)
 	}
 
-	err = rows.Err()
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
 	return &adverts, nil
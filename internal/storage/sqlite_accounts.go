@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// tables with rows of the user, deleted together with them
+// cascades are declared in migrations too, but this doesn't depend on foreign keys being enforced
+var userTables = []string{
+	"refresh_tokens",
+	"sessions",
+	"password_resets",
+	"recovery_codes",
+	"mfa_challenges",
+	"totp",
+	"api_keys",
+	"identities",
+	"oidc_states",
+	"bans",
+}
+
+// target of audit log entries about deleted users, the audit log trigger accepts only this change of target
+const deletedTarget = "user:[deleted]"
+
+// deletes the user with their adverts, reports, sessions and other data
+// audit log entries are kept, the log is append-only, but the user's login, id and ip addresses are erased from them
+// deletion is recorded as entry without personal data too
+func (s *Storage) DeleteUser(userID int64, entry *models.AuditEntry) error {
+	const op = "storage.sqlite.DeleteUser"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var login string
+	var regDate sql.NullTime
+
+	err = tx.QueryRow("SELECT login, regDate FROM users WHERE id = $1", userID).Scan(&login, &regDate)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// adverts reference the author by login, reports reference both adverts and users
+	_, err = tx.Exec(
+		"DELETE FROM reports WHERE reporterId = $1 OR advertId IN (SELECT id FROM adverts WHERE authorLogin = $2)",
+		userID,
+		login,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM adverts WHERE authorLogin = $1", login); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, table := range userTables {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE userId = $1", userID); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, table, err)
+		}
+	}
+
+	if err := eraseAuditData(tx, userID, login, regDate.Time); err != nil {
+		return fmt.Errorf("%s: audit log: %w", op, err)
+	}
+
+	entry.ActorId = 0
+	entry.ActorLogin = ""
+	entry.Target = deletedTarget
+	entry.IP = ""
+
+	if err := saveAuditEntry(tx, entry); err != nil {
+		return fmt.Errorf("%s: audit log: %w", op, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM users WHERE id = $1", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// erases personal data of the user from audit log
+// actions of others on the user keep actor, but lose the user's login
+// entries without actor, like failed logins, lose ip address, since it's likely the user's
+func eraseAuditData(ex executor, userID int64, login string, regDate time.Time) error {
+	_, err := ex.Exec(
+		"UPDATE audit_log SET actorId = NULL, actorLogin = '', ip = '' WHERE actorId = $1",
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// login may have belonged to a deleted user before, their entries are already erased
+	_, err = ex.Exec(
+		`UPDATE audit_log SET target = $1, ip = CASE WHEN actorId IS NULL THEN '' ELSE ip END
+		WHERE target = $2 COLLATE NOCASE AND createdAt >= $3`,
+		deletedTarget,
+		"user:"+login,
+		regDate.Local(),
+	)
+
+	return err
+}
+
+// gets everything stored about the user
+// it's read in one transaction, so that parts of the data are consistent with each other
+func (s *Storage) UserData(userID int64) (*models.UserData, error) {
+	const op = "storage.sqlite.UserData"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	user, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = $1", userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	data := &models.UserData{User: *user}
+
+	err = tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM totp WHERE userId = $1 AND enabledAt IS NOT NULL)",
+		userID,
+	).Scan(&data.TwoFactorEnabled)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := tx.Query("SELECT "+advertColumns+" FROM adverts WHERE authorLogin = $1 ORDER BY id", user.Login)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	adverts, err := scanAdverts(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	data.Adverts = *adverts
+
+	data.Reports, err = collect(tx, scanReport,
+		"SELECT id, advertId, reporterId, reason, comment, createdAt, resolvedAt FROM reports WHERE reporterId = $1 ORDER BY id",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: reports: %w", op, err)
+	}
+
+	data.Bans, err = collect(tx, scanBan, "SELECT "+banColumns+" FROM bans WHERE userId = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: bans: %w", op, err)
+	}
+
+	data.Sessions, err = collect(tx, scanSession,
+		"SELECT "+sessionColumns+" FROM sessions WHERE userId = $1 ORDER BY createdAt",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: sessions: %w", op, err)
+	}
+
+	data.APIKeys, err = collect(tx, scanAPIKey, "SELECT "+apiKeyColumns+" FROM api_keys WHERE userId = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: api keys: %w", op, err)
+	}
+
+	data.Identities, err = collect(tx, scanIdentity,
+		"SELECT "+identityColumns+" FROM identities WHERE userId = $1 ORDER BY id",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: identities: %w", op, err)
+	}
+
+	// only the user's own actions made since registration, entries of others may carry their personal data
+	data.AuditLog, err = collect(tx, scanAuditEntry,
+		"SELECT "+auditColumns+" FROM audit_log WHERE actorId = $1 AND createdAt >= $2 ORDER BY id",
+		userID,
+		user.RegDate.Local(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: audit log: %w", op, err)
+	}
+
+	return data, nil
+}
+
+// runs query and scans all rows of its result with scan
+func collect[T any](tx *sql.Tx, scan func(row scanner) (*T, error), query string, args ...any) ([]T, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, *item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// scans report from query result
+func scanReport(row scanner) (*models.Report, error) {
+	var rep models.Report
+	var resolvedAt sql.NullTime
+
+	err := row.Scan(&rep.Id, &rep.AdvertId, &rep.ReporterId, &rep.Reason, &rep.Comment, &rep.CreatedAt, &resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rep.ResolvedAt = resolvedAt.Time
+
+	return &rep, nil
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// how often last used time of an API key is updated
+const apiKeyTouchInterval = time.Minute
+
+// saves new API key
+func (s *Storage) SaveAPIKey(key *models.APIKey) (*models.APIKey, error) {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	res, err := s.db.Exec(
+		`INSERT INTO api_keys (userId, name, prefix, keyHash, scopes, createdAt, expiresAt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		key.UserId,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		strings.Join(key.Scopes, " "),
+		key.CreatedAt,
+		sql.NullTime{Time: key.ExpiresAt, Valid: !key.ExpiresAt.IsZero()},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key.Id = id
+
+	return key, nil
+}
+
+// gets API key by its prefix
+func (s *Storage) APIKey(prefix string) (*models.APIKey, error) {
+	const op = "storage.sqlite.APIKey"
+
+	row := s.db.QueryRow(
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE prefix = $1",
+		prefix,
+	)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// gets API keys of the user that are not revoked, newest first
+func (s *Storage) UserAPIKeys(userID int64) ([]models.APIKey, error) {
+	const op = "storage.sqlite.UserAPIKeys"
+
+	rows, err := s.db.Query(
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE userId = $1 AND revokedAt IS NULL ORDER BY createdAt DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// revokes API key of the user
+// ErrAPIKeyNotFound is returned if the user has no such key or it's already revoked
+func (s *Storage) RevokeAPIKey(userID, id int64, at time.Time) error {
+	const op = "storage.sqlite.RevokeAPIKey"
+
+	res, err := s.db.Exec(
+		"UPDATE api_keys SET revokedAt = $1 WHERE id = $2 AND userId = $3 AND revokedAt IS NULL",
+		at,
+		id,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// updates last used time of API key, at most once per apiKeyTouchInterval
+func (s *Storage) TouchAPIKey(id int64, at time.Time) error {
+	const op = "storage.sqlite.TouchAPIKey"
+
+	_, err := s.db.Exec(
+		"UPDATE api_keys SET lastUsedAt = $1 WHERE id = $2 AND (lastUsedAt IS NULL OR lastUsedAt < $3)",
+		at,
+		id,
+		at.Add(-apiKeyTouchInterval),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// columns of api_keys table in the order expected by scanAPIKey
+const apiKeyColumns = "id, userId, name, prefix, keyHash, scopes, createdAt, expiresAt, lastUsedAt, revokedAt"
+
+// scans API key from query result
+func scanAPIKey(row scanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+
+	err := row.Scan(
+		&key.Id, &key.UserId, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &key.CreatedAt,
+		&expiresAt, &lastUsedAt, &revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	key.ExpiresAt = expiresAt.Time
+	key.LastUsedAt = lastUsedAt.Time
+	key.RevokedAt = revokedAt.Time
+
+	return &key, nil
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// appends entry to audit log
+func (s *Storage) SaveAuditEntry(entry *models.AuditEntry) error {
+	const op = "storage.sqlite.SaveAuditEntry"
+
+	if err := saveAuditEntry(s.db, entry); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// appends entry to audit log with executor, so that it can be done in transaction
+func saveAuditEntry(ex executor, entry *models.AuditEntry) error {
+	var actorID sql.NullInt64
+	if entry.ActorId != 0 {
+		actorID = sql.NullInt64{Int64: entry.ActorId, Valid: true}
+	}
+
+	res, err := ex.Exec(
+		`INSERT INTO audit_log (event, actorId, actorLogin, target, details, ip, requestId, createdAt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		entry.Event,
+		actorID,
+		entry.ActorLogin,
+		entry.Target,
+		entry.Details,
+		entry.IP,
+		entry.RequestId,
+		entry.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	entry.Id = id
+
+	return nil
+}
+
+// gets audit entries matching the filter, newest first
+func (s *Storage) AuditLog(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const op = "storage.sqlite.AuditLog"
+
+	var conds []string
+	var args []any
+
+	// adds condition with the next placeholder
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, fmt.Sprintf("$%d", len(args))))
+	}
+
+	if filter.Event != "" {
+		where("event = %s", filter.Event)
+	}
+	if filter.ActorLogin != "" {
+		where("actorLogin = %s", filter.ActorLogin)
+	}
+	if filter.Target != "" {
+		where("target = %s", filter.Target)
+	}
+	if filter.IP != "" {
+		where("ip = %s", filter.IP)
+	}
+	if filter.RequestId != "" {
+		where("requestId = %s", filter.RequestId)
+	}
+	// times are stored as text, so bounds must be in the same time zone to compare correctly
+	if !filter.From.IsZero() {
+		where("createdAt >= %s", filter.From.Local())
+	}
+	if !filter.To.IsZero() {
+		where("createdAt < %s", filter.To.Local())
+	}
+
+	query := "SELECT " + auditColumns + " FROM audit_log"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+// columns of audit_log table in the order expected by scanAuditEntry
+const auditColumns = "id, event, actorId, actorLogin, target, details, ip, requestId, createdAt"
+
+// scans audit entry from query result
+func scanAuditEntry(row scanner) (*models.AuditEntry, error) {
+	var entry models.AuditEntry
+	var actorID sql.NullInt64
+
+	err := row.Scan(
+		&entry.Id, &entry.Event, &actorID, &entry.ActorLogin, &entry.Target,
+		&entry.Details, &entry.IP, &entry.RequestId, &entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.ActorId = actorID.Int64
+
+	return &entry, nil
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// records failed login attempt for the key (login or ip address)
+func (s *Storage) AddLoginFailure(key string, at time.Time) error {
+	const op = "storage.sqlite.AddLoginFailure"
+
+	_, err := s.db.Exec("INSERT INTO login_failures (key, at) VALUES ($1, $2)", key, at)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// gets times of failed login attempts for the key since the given time, oldest first
+// older failures are deleted
+func (s *Storage) LoginFailures(key string, since time.Time) ([]time.Time, error) {
+	const op = "storage.sqlite.LoginFailures"
+
+	_, err := s.db.Exec("DELETE FROM login_failures WHERE key = $1 AND at < $2", key, since)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query("SELECT at FROM login_failures WHERE key = $1 ORDER BY at", key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var failures []time.Time
+
+	for rows.Next() {
+		var at time.Time
+		if err := rows.Scan(&at); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		failures = append(failures, at)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return failures, nil
+}
+
+// forgets all failed login attempts for the key
+func (s *Storage) ResetLoginFailures(key string) error {
+	const op = "storage.sqlite.ResetLoginFailures"
+
+	_, err := s.db.Exec("DELETE FROM login_failures WHERE key = $1", key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// deletes failed login attempts of all keys before the given time
+func (s *Storage) PruneLoginFailures(before time.Time) error {
+	const op = "storage.sqlite.PruneLoginFailures"
+
+	_, err := s.db.Exec("DELETE FROM login_failures WHERE at < $1", before)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
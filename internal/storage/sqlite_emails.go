@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// changes email of the user, new email is not verified
+// empty email removes it, unverified emails may be shared by several users
+func (s *Storage) SetEmail(userID int64, email string) error {
+	const op = "storage.sqlite.SetEmail"
+
+	res, err := s.db.Exec(
+		"UPDATE users SET email = $1, emailVerifiedAt = NULL WHERE id = $2",
+		nullString(email),
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// marks email of the user verified
+// ErrUserNotFound is returned if the user doesn't have this email anymore,
+// ErrEmailExists if another user has verified it before
+func (s *Storage) VerifyEmail(userID int64, email string, at time.Time) error {
+	const op = "storage.sqlite.VerifyEmail"
+
+	res, err := s.db.Exec(
+		"UPDATE users SET emailVerifiedAt = $1 WHERE id = $2 AND email = $3",
+		at,
+		userID,
+		email,
+	)
+	if err != nil {
+		if isEmailTaken(err) {
+			return fmt.Errorf("%s: %w", op, ErrEmailExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// checks if error is a violation of unique email constraint
+func isEmailTaken(err error) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) &&
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique &&
+		strings.Contains(sqliteErr.Error(), "users.email")
+}
+
+// converts empty string to NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
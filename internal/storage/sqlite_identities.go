@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// saves user created on first login with external provider together with their identity
+// email is saved as verified if its EmailVerifiedAt is set
+func (s *Storage) SaveUserWithIdentity(u *models.User, identity *models.Identity) (*models.User, error) {
+	const op = "storage.sqlite.SaveUserWithIdentity"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if u.Role == "" {
+		u.Role = models.RoleUser
+	}
+
+	res, err := tx.Exec(
+		`INSERT INTO users (login, loginKey, passHash, regDate, role, email, emailVerifiedAt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		u.Login,
+		nullString(u.LoginKey),
+		u.PassHash,
+		u.RegDate,
+		u.Role,
+		nullString(u.Email),
+		sql.NullTime{Time: u.EmailVerifiedAt, Valid: !u.EmailVerifiedAt.IsZero()},
+	)
+	if err != nil {
+		if isEmailTaken(err) {
+			return nil, fmt.Errorf("%s: %w", op, ErrEmailExists)
+		}
+
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%s: %w", op, ErrUserExists)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	userID, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	identity.UserId = userID
+
+	identityID, err := insertIdentity(tx, identity)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	u.Id = userID
+	identity.Id = identityID
+
+	return u, nil
+}
+
+// links identity at external provider to existing user
+// ErrIdentityExists is returned if the identity is linked to someone
+// or the user already has an identity at the provider
+func (s *Storage) SaveIdentity(identity *models.Identity) (*models.Identity, error) {
+	const op = "storage.sqlite.SaveIdentity"
+
+	id, err := insertIdentity(s.db, identity)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	identity.Id = id
+
+	return identity, nil
+}
+
+// gets identity by provider and the user's identifier at it
+func (s *Storage) Identity(provider, subject string) (*models.Identity, error) {
+	const op = "storage.sqlite.Identity"
+
+	row := s.db.QueryRow(
+		"SELECT "+identityColumns+" FROM identities WHERE provider = $1 AND subject = $2",
+		provider,
+		subject,
+	)
+
+	identity, err := scanIdentity(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrIdentityNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return identity, nil
+}
+
+// gets identities linked to the user
+func (s *Storage) UserIdentities(userID int64) ([]models.Identity, error) {
+	const op = "storage.sqlite.UserIdentities"
+
+	rows, err := s.db.Query(
+		"SELECT "+identityColumns+" FROM identities WHERE userId = $1 ORDER BY createdAt",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	identities := []models.Identity{}
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		identities = append(identities, *identity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return identities, nil
+}
+
+// unlinks the user's identity at the provider
+// ErrIdentityNotFound is returned if there's none
+func (s *Storage) DeleteIdentity(userID int64, provider string) error {
+	const op = "storage.sqlite.DeleteIdentity"
+
+	res, err := s.db.Exec(
+		"DELETE FROM identities WHERE userId = $1 AND provider = $2",
+		userID,
+		provider,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrIdentityNotFound)
+	}
+
+	return nil
+}
+
+// saves authorization request sent to provider
+func (s *Storage) SaveOIDCState(state *models.OIDCState) (*models.OIDCState, error) {
+	const op = "storage.sqlite.SaveOIDCState"
+
+	res, err := s.db.Exec(
+		`INSERT INTO oidc_states (stateHash, provider, nonce, verifier, userId, createdAt, expiresAt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		state.StateHash,
+		state.Provider,
+		state.Nonce,
+		state.Verifier,
+		sql.NullInt64{Int64: state.UserId, Valid: state.UserId != 0},
+		state.CreatedAt,
+		state.ExpiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	state.Id = id
+
+	return state, nil
+}
+
+// gets authorization request by hash of its state
+func (s *Storage) OIDCState(stateHash string) (*models.OIDCState, error) {
+	const op = "storage.sqlite.OIDCState"
+
+	row := s.db.QueryRow(
+		`SELECT id, stateHash, provider, nonce, verifier, userId, createdAt, expiresAt, usedAt
+		FROM oidc_states WHERE stateHash = $1`,
+		stateHash,
+	)
+
+	var state models.OIDCState
+	var userID sql.NullInt64
+	var usedAt sql.NullTime
+
+	err := row.Scan(
+		&state.Id, &state.StateHash, &state.Provider, &state.Nonce, &state.Verifier, &userID,
+		&state.CreatedAt, &state.ExpiresAt, &usedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrTokenNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	state.UserId = userID.Int64
+	state.UsedAt = usedAt.Time
+
+	return &state, nil
+}
+
+// marks authorization request used
+// ErrTokenReused is returned if it has already been used
+func (s *Storage) UseOIDCState(id int64, at time.Time) error {
+	const op = "storage.sqlite.UseOIDCState"
+
+	res, err := s.db.Exec(
+		"UPDATE oidc_states SET usedAt = $1 WHERE id = $2 AND usedAt IS NULL",
+		at,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrTokenReused)
+	}
+
+	return nil
+}
+
+// inserts identity and returns its id
+func insertIdentity(db executor, identity *models.Identity) (int64, error) {
+	res, err := db.Exec(
+		"INSERT INTO identities (userId, provider, subject, email, createdAt) VALUES ($1, $2, $3, $4, $5)",
+		identity.UserId,
+		identity.Provider,
+		identity.Subject,
+		nullString(identity.Email),
+		identity.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, ErrIdentityExists
+		}
+
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// reports whether query failed because of unique constraint
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// columns of identities table in the order expected by scanIdentity
+const identityColumns = "id, userId, provider, subject, email, createdAt"
+
+// scans identity from query result
+func scanIdentity(row scanner) (*models.Identity, error) {
+	var identity models.Identity
+	var email sql.NullString
+
+	err := row.Scan(&identity.Id, &identity.UserId, &identity.Provider, &identity.Subject, &email, &identity.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	identity.Email = email.String
+
+	return &identity, nil
+}
@@ -0,0 +1,306 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// saves report about an advert
+// every user can report an advert only once
+func (s *Storage) SaveReport(rep *models.Report) (*models.Report, error) {
+	const op = "storage.sqlite.SaveReport"
+
+	res, err := s.db.Exec(
+		`INSERT INTO reports (advertId, reporterId, reason, comment, createdAt)
+		VALUES ($1, $2, $3, $4, $5)`,
+		rep.AdvertId,
+		rep.ReporterId,
+		rep.Reason,
+		rep.Comment,
+		rep.CreatedAt,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return nil, fmt.Errorf("%s: %w", op, ErrAlreadyReported)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rep.Id = id
+
+	return rep, nil
+}
+
+// counts reports about the advert not yet resolved by moderators
+func (s *Storage) OpenReportsCount(advertID int64) (int, error) {
+	const op = "storage.sqlite.OpenReportsCount"
+
+	row := s.db.QueryRow(
+		"SELECT COUNT(*) FROM reports WHERE advertId = $1 AND resolvedAt IS NULL",
+		advertID,
+	)
+
+	var count int
+	if err := row.Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// gets reported adverts waiting for moderation, most reported first
+func (s *Storage) ModerationQueue() ([]models.ModerationItem, error) {
+	const op = "storage.sqlite.ModerationQueue"
+
+	rows, err := s.db.Query(
+		`SELECT a.id, a.header, a.body, a.imageURL, a.price, a.date, a.authorLogin, a.status, a.moderationReason,
+			r.reportsCount, r.reasons
+		FROM adverts a JOIN (
+			SELECT advertId, COUNT(*) AS reportsCount, GROUP_CONCAT(DISTINCT reason) AS reasons, MIN(id) AS firstReport
+			FROM reports WHERE resolvedAt IS NULL GROUP BY advertId
+		) r ON r.advertId = a.id
+		ORDER BY r.reportsCount DESC, r.firstReport`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var items []models.ModerationItem
+
+	for rows.Next() {
+		var item models.ModerationItem
+		var reasons string
+
+		ad := &item.Advert
+
+		err := rows.Scan(
+			&ad.Id,
+			&ad.Header,
+			&ad.Body,
+			&ad.ImageURL,
+			&ad.Price,
+			&ad.Date,
+			&ad.AuthorLogin,
+			&ad.Status,
+			&ad.ModerationReason,
+			&item.ReportsCount,
+			&reasons,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, reason := range strings.Split(reasons, ",") {
+			item.Reasons = append(item.Reasons, models.ReportReason(reason))
+		}
+
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
+
+// sets status of the advert decided by moderator and resolves its reports
+// reason is shown to the author of the advert
+func (s *Storage) ModerateAdvert(id int64, status, reason string) error {
+	const op = "storage.sqlite.ModerateAdvert"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"UPDATE adverts SET status = $1, moderationReason = $2 WHERE id = $3",
+		status,
+		reason,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrAdvertNotFound)
+	}
+
+	_, err = tx.Exec(
+		"UPDATE reports SET resolvedAt = $1 WHERE advertId = $2 AND resolvedAt IS NULL",
+		time.Now(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// saves ban of the user
+func (s *Storage) SaveBan(ban *models.Ban) (*models.Ban, error) {
+	const op = "storage.sqlite.SaveBan"
+
+	var expiresAt sql.NullTime
+	if !ban.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: ban.ExpiresAt, Valid: true}
+	}
+
+	var createdBy sql.NullInt64
+	if ban.CreatedBy != 0 {
+		createdBy = sql.NullInt64{Int64: ban.CreatedBy, Valid: true}
+	}
+
+	res, err := s.db.Exec(
+		`INSERT INTO bans (userId, reason, createdAt, createdBy, expiresAt)
+		VALUES ($1, $2, $3, $4, $5)`,
+		ban.UserId,
+		ban.Reason,
+		ban.CreatedAt,
+		createdBy,
+		expiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ban.Id = id
+
+	return ban, nil
+}
+
+// gets ban of the user that is in effect now
+// the one expiring last is returned if there are several
+func (s *Storage) ActiveBan(userID int64) (*models.Ban, error) {
+	const op = "storage.sqlite.ActiveBan"
+
+	row := s.db.QueryRow(
+		`SELECT `+banColumns+` FROM bans
+		WHERE userId = $1 AND liftedAt IS NULL AND (expiresAt IS NULL OR expiresAt > $2)
+		ORDER BY expiresAt IS NULL DESC, expiresAt DESC
+		LIMIT 1`,
+		userID,
+		time.Now(),
+	)
+
+	ban, err := scanBan(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrBanNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ban, nil
+}
+
+// lifts all bans of the user that are in effect now
+func (s *Storage) LiftBans(userID int64, at time.Time) error {
+	const op = "storage.sqlite.LiftBans"
+
+	res, err := s.db.Exec(
+		`UPDATE bans SET liftedAt = $1
+		WHERE userId = $2 AND liftedAt IS NULL AND (expiresAt IS NULL OR expiresAt > $1)`,
+		at,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrBanNotFound)
+	}
+
+	return nil
+}
+
+// saves user's appeal against the ban
+func (s *Storage) SetBanAppeal(id int64, appeal string, at time.Time) error {
+	const op = "storage.sqlite.SetBanAppeal"
+
+	res, err := s.db.Exec(
+		"UPDATE bans SET appeal = $1, appealedAt = $2 WHERE id = $3",
+		appeal,
+		at,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrBanNotFound)
+	}
+
+	return nil
+}
+
+// columns of bans table in the order expected by scanBan
+const banColumns = "id, userId, reason, createdAt, createdBy, expiresAt, liftedAt, appeal, appealedAt"
+
+// scans ban from query result
+func scanBan(row scanner) (*models.Ban, error) {
+	var ban models.Ban
+	var createdBy sql.NullInt64
+	var expiresAt, liftedAt, appealedAt sql.NullTime
+
+	err := row.Scan(
+		&ban.Id, &ban.UserId, &ban.Reason, &ban.CreatedAt, &createdBy,
+		&expiresAt, &liftedAt, &ban.Appeal, &appealedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ban.CreatedBy = createdBy.Int64
+	ban.ExpiresAt = expiresAt.Time
+	ban.LiftedAt = liftedAt.Time
+	ban.AppealedAt = appealedAt.Time
+
+	return &ban, nil
+}
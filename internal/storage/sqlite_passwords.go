@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// changes password hash of the user
+func (s *Storage) SetPassword(userID int64, passHash []byte) error {
+	const op = "storage.sqlite.SetPassword"
+
+	res, err := s.db.Exec("UPDATE users SET passHash = $1 WHERE id = $2", passHash, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// saves password reset token
+// previous unused tokens of the user stop working
+func (s *Storage) SavePasswordReset(pr *models.PasswordReset) (*models.PasswordReset, error) {
+	const op = "storage.sqlite.SavePasswordReset"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"UPDATE password_resets SET usedAt = $1 WHERE userId = $2 AND usedAt IS NULL",
+		pr.CreatedAt,
+		pr.UserId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.Exec(
+		"INSERT INTO password_resets (userId, tokenHash, createdAt, expiresAt) VALUES ($1, $2, $3, $4)",
+		pr.UserId,
+		pr.TokenHash,
+		pr.CreatedAt,
+		pr.ExpiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pr.Id = id
+
+	return pr, nil
+}
+
+// gets password reset token by its hash
+func (s *Storage) PasswordReset(tokenHash string) (*models.PasswordReset, error) {
+	const op = "storage.sqlite.PasswordReset"
+
+	row := s.db.QueryRow(
+		"SELECT id, userId, tokenHash, createdAt, expiresAt, usedAt FROM password_resets WHERE tokenHash = $1",
+		tokenHash,
+	)
+
+	var pr models.PasswordReset
+	var usedAt sql.NullTime
+
+	err := row.Scan(&pr.Id, &pr.UserId, &pr.TokenHash, &pr.CreatedAt, &pr.ExpiresAt, &usedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrTokenNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pr.UsedAt = usedAt.Time
+
+	return &pr, nil
+}
+
+// uses password reset token to set new password and revokes all sessions of the user
+// ErrTokenReused is returned if the token has already been used
+func (s *Storage) ResetPassword(pr *models.PasswordReset, passHash []byte, at time.Time) error {
+	const op = "storage.sqlite.ResetPassword"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// marking token used only if it wasn't, so that it can't be used twice concurrently
+	res, err := tx.Exec(
+		"UPDATE password_resets SET usedAt = $1 WHERE id = $2 AND usedAt IS NULL",
+		at,
+		pr.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrTokenReused)
+	}
+
+	_, err = tx.Exec("UPDATE users SET passHash = $1 WHERE id = $2", passHash, pr.UserId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := revokeSessionsTx(tx, at, "userId = $2", pr.UserId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// how often last seen time of a session is updated
+const sessionTouchInterval = time.Minute
+
+// saves new session together with its first refresh token
+func (s *Storage) CreateSession(session *models.Session, rt *models.RefreshToken) error {
+	const op = "storage.sqlite.CreateSession"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO sessions (id, userId, userAgent, ip, createdAt, lastSeenAt)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		session.Id,
+		session.UserId,
+		session.UserAgent,
+		session.IP,
+		session.CreatedAt,
+		session.LastSeenAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := insertRefreshToken(tx, rt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rt.Id = id
+
+	return nil
+}
+
+// gets session with the given id from storage
+func (s *Storage) Session(id string) (*models.Session, error) {
+	const op = "storage.sqlite.Session"
+
+	row := s.db.QueryRow(
+		"SELECT "+sessionColumns+" FROM sessions WHERE id = $1",
+		id,
+	)
+
+	session, err := scanSession(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrSessionNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+// gets active sessions of the user
+// session is active until it's revoked or its refresh token expires
+func (s *Storage) UserSessions(userID int64) ([]models.Session, error) {
+	const op = "storage.sqlite.UserSessions"
+
+	rows, err := s.db.Query(
+		`SELECT `+sessionColumns+` FROM sessions
+		WHERE userId = $1 AND revokedAt IS NULL AND EXISTS (
+			SELECT 1 FROM refresh_tokens
+			WHERE refresh_tokens.sessionId = sessions.id AND usedAt IS NULL AND revokedAt IS NULL AND expiresAt > $2
+		)
+		ORDER BY lastSeenAt DESC`,
+		userID,
+		time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		sessions = append(sessions, *session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// updates last seen time of the session
+// to spare writes it's done at most once per sessionTouchInterval
+func (s *Storage) TouchSession(id string, at time.Time) error {
+	const op = "storage.sqlite.TouchSession"
+
+	_, err := s.db.Exec(
+		"UPDATE sessions SET lastSeenAt = $1 WHERE id = $2 AND lastSeenAt < $3",
+		at,
+		id,
+		at.Add(-sessionTouchInterval),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// revokes session and all its refresh tokens
+func (s *Storage) RevokeSession(id string) error {
+	const op = "storage.sqlite.RevokeSession"
+
+	err := s.revokeSessions("id = $2", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// revokes all sessions of the user and their refresh tokens
+func (s *Storage) RevokeUserSessions(userID int64) error {
+	const op = "storage.sqlite.RevokeUserSessions"
+
+	err := s.revokeSessions("userId = $2", userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// revokes all sessions of the user except the given one
+func (s *Storage) RevokeOtherSessions(userID int64, keepID string) error {
+	const op = "storage.sqlite.RevokeOtherSessions"
+
+	err := s.revokeSessions("userId = $2 AND id != $3", userID, keepID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// revokes sessions matching the condition on sessions table
+// condition refers to its arguments starting with $2
+func (s *Storage) revokeSessions(cond string, args ...any) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := revokeSessionsTx(tx, time.Now(), cond, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// revokes sessions matching the condition and their refresh tokens within transaction
+func revokeSessionsTx(tx executor, now time.Time, cond string, args ...any) error {
+	_, err := tx.Exec(
+		`UPDATE refresh_tokens SET revokedAt = $1
+		WHERE revokedAt IS NULL AND sessionId IN (SELECT id FROM sessions WHERE `+cond+`)`,
+		append([]any{now}, args...)...,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE sessions SET revokedAt = $1 WHERE revokedAt IS NULL AND "+cond,
+		append([]any{now}, args...)...,
+	)
+
+	return err
+}
+
+// columns of sessions table in the order expected by scanSession
+const sessionColumns = "id, userId, userAgent, ip, createdAt, lastSeenAt, revokedAt"
+
+// scans session from query result
+func scanSession(row scanner) (*models.Session, error) {
+	var session models.Session
+	var revokedAt sql.NullTime
+
+	err := row.Scan(
+		&session.Id,
+		&session.UserId,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session.RevokedAt = revokedAt.Time
+
+	return &session, nil
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// gets refresh token with the given hash from storage
+func (s *Storage) RefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	const op = "storage.sqlite.RefreshToken"
+
+	row := s.db.QueryRow(
+		`SELECT id, userId, sessionId, tokenHash, createdAt, expiresAt, usedAt, revokedAt
+		FROM refresh_tokens WHERE tokenHash = $1`,
+		tokenHash,
+	)
+
+	var rt models.RefreshToken
+	var usedAt, revokedAt sql.NullTime
+
+	err := row.Scan(&rt.Id, &rt.UserId, &rt.SessionId, &rt.TokenHash, &rt.CreatedAt, &rt.ExpiresAt, &usedAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrTokenNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rt.UsedAt = usedAt.Time
+	rt.RevokedAt = revokedAt.Time
+
+	return &rt, nil
+}
+
+// marks refresh token with the given id as used and saves the next token of its session
+// fails with ErrTokenReused if the token was already used or revoked
+func (s *Storage) RotateRefreshToken(usedID int64, next *models.RefreshToken) (*models.RefreshToken, error) {
+	const op = "storage.sqlite.RotateRefreshToken"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// mark token as used only if nobody has used it before
+	res, err := tx.Exec(
+		"UPDATE refresh_tokens SET usedAt = $1 WHERE id = $2 AND usedAt IS NULL AND revokedAt IS NULL",
+		next.CreatedAt,
+		usedID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrTokenReused)
+	}
+
+	id, err := insertRefreshToken(tx, next)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	next.Id = id
+
+	return next, nil
+}
+
+// inserts refresh token and returns its id
+func insertRefreshToken(db executor, rt *models.RefreshToken) (int64, error) {
+	res, err := db.Exec(
+		`INSERT INTO refresh_tokens (userId, sessionId, tokenHash, createdAt, expiresAt)
+		VALUES ($1, $2, $3, $4, $5)`,
+		rt.UserId,
+		rt.SessionId,
+		rt.TokenHash,
+		rt.CreatedAt,
+		rt.ExpiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
@@ -0,0 +1,364 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rigbyel/ad-market/internal/models"
+)
+
+// saves pending authenticator secret of the user along with hashes of recovery codes
+// secret and codes of previous unconfirmed enrollment are replaced
+// ErrTOTPEnabled is returned if two-factor authentication is already enabled
+func (s *Storage) SaveTOTP(t *models.TOTP, recoveryHashes []string) error {
+	const op = "storage.sqlite.SaveTOTP"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`INSERT INTO totp (userId, secret, createdAt) VALUES ($1, $2, $3)
+		ON CONFLICT (userId) DO UPDATE SET secret = excluded.secret, createdAt = excluded.createdAt
+		WHERE enabledAt IS NULL`,
+		t.UserId,
+		t.Secret,
+		t.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrTOTPEnabled)
+	}
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE userId = $1", t.UserId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, hash := range recoveryHashes {
+		_, err := tx.Exec("INSERT INTO recovery_codes (userId, codeHash) VALUES ($1, $2)", t.UserId, hash)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// gets authenticator secret of the user
+func (s *Storage) TOTP(userID int64) (*models.TOTP, error) {
+	const op = "storage.sqlite.TOTP"
+
+	row := s.db.QueryRow(
+		"SELECT userId, secret, createdAt, enabledAt, lastStep FROM totp WHERE userId = $1",
+		userID,
+	)
+
+	var t models.TOTP
+	var enabledAt sql.NullTime
+
+	err := row.Scan(&t.UserId, &t.Secret, &t.CreatedAt, &enabledAt, &t.LastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrTOTPNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.EnabledAt = enabledAt.Time
+
+	return &t, nil
+}
+
+// enables two-factor authentication confirmed by code of the given time step
+func (s *Storage) EnableTOTP(userID int64, step int64, at time.Time) error {
+	const op = "storage.sqlite.EnableTOTP"
+
+	res, err := s.db.Exec(
+		"UPDATE totp SET enabledAt = $1, lastStep = $2 WHERE userId = $3 AND enabledAt IS NULL",
+		at,
+		step,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrTOTPNotFound)
+	}
+
+	return nil
+}
+
+// marks token of login waiting for the second factor used together with authenticator code of the step,
+// neither is used if the other is rejected, so that a failed attempt doesn't burn the code
+// ErrTokenReused is returned if the token has already been used
+// ErrCodeReused is returned if code of this or later step has already been accepted
+func (s *Storage) UseMFAChallengeWithTOTP(challengeID, userID int64, step int64, at time.Time) error {
+	const op = "storage.sqlite.UseMFAChallengeWithTOTP"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := useMFAChallenge(tx, challengeID, at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := useTOTPStep(tx, userID, step); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// marks token of login waiting for the second factor used together with recovery code
+// neither is used if the other is rejected, so that a failed or replayed token doesn't burn the code
+// ErrTokenReused is returned if the token has already been used
+// ErrRecoveryCodeNotFound is returned if the user has no such unused code
+func (s *Storage) UseMFAChallengeWithRecoveryCode(challengeID, userID int64, codeHash string, at time.Time) error {
+	const op = "storage.sqlite.UseMFAChallengeWithRecoveryCode"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := useMFAChallenge(tx, challengeID, at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := useRecoveryCode(tx, userID, codeHash, at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// marks time step as used, so that code can't be replayed
+// ErrCodeReused is returned if code of this or later step has already been accepted
+func useTOTPStep(ex executor, userID int64, step int64) error {
+	res, err := ex.Exec(
+		"UPDATE totp SET lastStep = $1 WHERE userId = $2 AND lastStep < $1",
+		step,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrCodeReused
+	}
+
+	return nil
+}
+
+// marks recovery code used
+// ErrRecoveryCodeNotFound is returned if the user has no such unused code
+func useRecoveryCode(ex executor, userID int64, codeHash string, at time.Time) error {
+	res, err := ex.Exec(
+		"UPDATE recovery_codes SET usedAt = $1 WHERE userId = $2 AND codeHash = $3 AND usedAt IS NULL",
+		at,
+		userID,
+		codeHash,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+// counts unused recovery codes of the user
+func (s *Storage) RecoveryCodesLeft(userID int64) (int, error) {
+	const op = "storage.sqlite.RecoveryCodesLeft"
+
+	var count int
+
+	err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM recovery_codes WHERE userId = $1 AND usedAt IS NULL",
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// removes authenticator secret and recovery codes of the user
+func (s *Storage) DeleteTOTP(userID int64) error {
+	const op = "storage.sqlite.DeleteTOTP"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM totp WHERE userId = $1", userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrTOTPNotFound)
+	}
+
+	statements := []string{
+		"DELETE FROM recovery_codes WHERE userId = $1",
+		"DELETE FROM mfa_challenges WHERE userId = $1",
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// saves token of login waiting for the second factor
+func (s *Storage) SaveMFAChallenge(c *models.MFAChallenge) (*models.MFAChallenge, error) {
+	const op = "storage.sqlite.SaveMFAChallenge"
+
+	res, err := s.db.Exec(
+		"INSERT INTO mfa_challenges (userId, tokenHash, createdAt, expiresAt) VALUES ($1, $2, $3, $4)",
+		c.UserId,
+		c.TokenHash,
+		c.CreatedAt,
+		c.ExpiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	c.Id = id
+
+	return c, nil
+}
+
+// gets token of login waiting for the second factor by its hash
+func (s *Storage) MFAChallenge(tokenHash string) (*models.MFAChallenge, error) {
+	const op = "storage.sqlite.MFAChallenge"
+
+	row := s.db.QueryRow(
+		"SELECT id, userId, tokenHash, createdAt, expiresAt, usedAt, attempts FROM mfa_challenges WHERE tokenHash = $1",
+		tokenHash,
+	)
+
+	var c models.MFAChallenge
+	var usedAt sql.NullTime
+
+	err := row.Scan(&c.Id, &c.UserId, &c.TokenHash, &c.CreatedAt, &c.ExpiresAt, &usedAt, &c.Attempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrTokenNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	c.UsedAt = usedAt.Time
+
+	return &c, nil
+}
+
+// counts wrong code entered for the login waiting for the second factor
+func (s *Storage) FailMFAChallenge(id int64) error {
+	const op = "storage.sqlite.FailMFAChallenge"
+
+	_, err := s.db.Exec("UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// marks token of login waiting for the second factor used
+// ErrTokenReused is returned if the token has already been used
+func useMFAChallenge(ex executor, id int64, at time.Time) error {
+	res, err := ex.Exec(
+		"UPDATE mfa_challenges SET usedAt = $1 WHERE id = $2 AND usedAt IS NULL",
+		at,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrTokenReused
+	}
+
+	return nil
+}
@@ -1,10 +1,31 @@
 package storage
 
 import (
-	"errors"
+	"github.com/rigbyel/ad-market/internal/lib/errkind"
 )
 
+// errors of storage are typed, so that handlers derive HTTP status from them
 var (
-	ErrUserExists   = errors.New("user already exists")
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errkind.New(errkind.Conflict, "user already exists")
+	ErrUserNotFound = errkind.New(errkind.NotFound, "user not found")
+	ErrEmailExists  = errkind.New(errkind.Conflict, "email already used")
+
+	ErrAdvertNotFound = errkind.New(errkind.NotFound, "advert not found")
+
+	ErrAlreadyReported = errkind.New(errkind.Conflict, "advert already reported by user")
+	ErrBanNotFound     = errkind.New(errkind.NotFound, "ban not found")
+
+	ErrTokenNotFound = errkind.New(errkind.NotFound, "token not found")
+	ErrTokenReused   = errkind.New(errkind.Conflict, "token already used")
+
+	ErrSessionNotFound = errkind.New(errkind.NotFound, "session not found")
+	ErrAPIKeyNotFound  = errkind.New(errkind.NotFound, "api key not found")
+
+	ErrIdentityExists   = errkind.New(errkind.Conflict, "identity already linked")
+	ErrIdentityNotFound = errkind.New(errkind.NotFound, "identity not found")
+
+	ErrTOTPNotFound         = errkind.New(errkind.NotFound, "two-factor authentication not set up")
+	ErrTOTPEnabled          = errkind.New(errkind.Conflict, "two-factor authentication already enabled")
+	ErrCodeReused           = errkind.New(errkind.Conflict, "code already used")
+	ErrRecoveryCodeNotFound = errkind.New(errkind.NotFound, "recovery code not found")
 )
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS login_failures (
+    id INTEGER PRIMARY KEY,
+    key TEXT NOT NULL,
+    at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_key ON login_failures(key, at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS password_resets (
+    id INTEGER PRIMARY KEY,
+    userId INTEGER NOT NULL,
+    tokenHash TEXT NOT NULL UNIQUE,
+    createdAt DATETIME NOT NULL,
+    expiresAt DATETIME NOT NULL,
+    usedAt DATETIME,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user ON password_resets(userId);
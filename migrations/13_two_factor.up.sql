@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS totp (
+    userId INTEGER PRIMARY KEY,
+    secret TEXT NOT NULL,
+    createdAt DATETIME NOT NULL,
+    enabledAt DATETIME,
+    lastStep INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id INTEGER PRIMARY KEY,
+    userId INTEGER NOT NULL,
+    codeHash TEXT NOT NULL,
+    usedAt DATETIME,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes(userId);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id INTEGER PRIMARY KEY,
+    userId INTEGER NOT NULL,
+    tokenHash TEXT NOT NULL UNIQUE,
+    createdAt DATETIME NOT NULL,
+    expiresAt DATETIME NOT NULL,
+    usedAt DATETIME,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_login_key;
+
+ALTER TABLE users DROP COLUMN loginKey;
@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN loginKey TEXT;
+
+-- existing logins are latin, so lower case is their key
+-- if some of them differ only in case, the oldest account gets the key and the others keep working without it
+UPDATE users SET loginKey = lower(login)
+WHERE id IN (SELECT MIN(id) FROM users GROUP BY lower(login));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_login_key ON users(loginKey) WHERE loginKey IS NOT NULL;
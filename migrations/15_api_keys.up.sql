@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY,
+    userId INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL UNIQUE,
+    keyHash TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    createdAt DATETIME NOT NULL,
+    expiresAt DATETIME,
+    lastUsedAt DATETIME,
+    revokedAt DATETIME,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(userId);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS identities (
+    id INTEGER PRIMARY KEY,
+    userId INTEGER NOT NULL,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT,
+    createdAt DATETIME NOT NULL,
+    UNIQUE (provider, subject),
+    UNIQUE (userId, provider),
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS oidc_states (
+    id INTEGER PRIMARY KEY,
+    stateHash TEXT NOT NULL UNIQUE,
+    provider TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    verifier TEXT NOT NULL,
+    userId INTEGER,
+    createdAt DATETIME NOT NULL,
+    expiresAt DATETIME NOT NULL,
+    usedAt DATETIME,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
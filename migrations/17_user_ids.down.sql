@@ -0,0 +1,29 @@
+DROP TRIGGER IF EXISTS audit_log_no_update;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit log is append-only');
+END;
+
+CREATE TABLE users_old (
+    id INTEGER PRIMARY KEY,
+    login TEXT NOT NULL UNIQUE,
+    passHash BLOB NOT NULL,
+    regDate DATETIME,
+    bio TEXT NOT NULL DEFAULT '',
+    avatarURL TEXT NOT NULL DEFAULT '',
+    rating REAL NOT NULL DEFAULT 0,
+    role TEXT NOT NULL DEFAULT 'user',
+    email TEXT,
+    emailVerifiedAt DATETIME,
+    loginKey TEXT
+);
+
+INSERT INTO users_old (id, login, passHash, regDate, bio, avatarURL, rating, role, email, emailVerifiedAt, loginKey)
+SELECT id, login, passHash, regDate, bio, avatarURL, rating, role, email, emailVerifiedAt, loginKey FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE email IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_login_key ON users(loginKey) WHERE loginKey IS NOT NULL;
@@ -0,0 +1,47 @@
+-- ids of deleted users are never given to new ones, so that nothing left of an old account is attributed to a new one
+-- sqlite can't change primary key of a table, so it's rebuilt
+-- migrations run without foreign keys enforced, otherwise dropping users would cascade to their rows
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    login TEXT NOT NULL UNIQUE,
+    passHash BLOB NOT NULL,
+    regDate DATETIME,
+    bio TEXT NOT NULL DEFAULT '',
+    avatarURL TEXT NOT NULL DEFAULT '',
+    rating REAL NOT NULL DEFAULT 0,
+    role TEXT NOT NULL DEFAULT 'user',
+    email TEXT,
+    emailVerifiedAt DATETIME,
+    loginKey TEXT
+);
+
+INSERT INTO users_new (id, login, passHash, regDate, bio, avatarURL, rating, role, email, emailVerifiedAt, loginKey)
+SELECT id, login, passHash, regDate, bio, avatarURL, rating, role, email, emailVerifiedAt, loginKey FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE email IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_login_key ON users(loginKey) WHERE loginKey IS NOT NULL;
+
+-- ids of users deleted before are skipped too, audit log still may refer to them
+DELETE FROM sqlite_sequence WHERE name = 'users';
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'users', MAX(IFNULL((SELECT MAX(id) FROM users), 0), IFNULL((SELECT MAX(actorId) FROM audit_log), 0));
+
+-- audit log is append-only, but personal data of deleted users is erased from it
+DROP TRIGGER IF EXISTS audit_log_no_update;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+WHEN NEW.id IS NOT OLD.id
+    OR NEW.event IS NOT OLD.event
+    OR NEW.details IS NOT OLD.details
+    OR NEW.requestId IS NOT OLD.requestId
+    OR NEW.createdAt IS NOT OLD.createdAt
+    OR (NEW.actorId IS NOT OLD.actorId AND NEW.actorId IS NOT NULL)
+    OR (NEW.actorLogin IS NOT OLD.actorLogin AND NEW.actorLogin != '')
+    OR (NEW.ip IS NOT OLD.ip AND NEW.ip != '')
+    OR (NEW.target IS NOT OLD.target AND NEW.target != 'user:[deleted]')
+BEGIN
+    SELECT RAISE(ABORT, 'audit log is append-only');
+END;
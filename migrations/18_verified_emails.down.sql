@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_users_email;
+
+-- unverified duplicates of other accounts' addresses are removed
+UPDATE users SET email = NULL
+WHERE emailVerifiedAt IS NULL AND EXISTS (
+    SELECT 1 FROM users other
+    WHERE other.email = users.email AND other.id != users.id
+        AND (other.emailVerifiedAt IS NOT NULL OR other.id < users.id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE email IS NOT NULL;
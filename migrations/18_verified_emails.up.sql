@@ -0,0 +1,5 @@
+-- only verified emails are unique, so that nobody can block an address by giving it first
+-- conflicts are resolved when the address is verified
+DROP INDEX IF EXISTS idx_users_email;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE emailVerifiedAt IS NOT NULL;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_login_failures_at ON login_failures(at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_adverts_author;
+
+ALTER TABLE users DROP COLUMN rating;
+ALTER TABLE users DROP COLUMN avatarURL;
+ALTER TABLE users DROP COLUMN bio;
+ALTER TABLE users DROP COLUMN regDate;
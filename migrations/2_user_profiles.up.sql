@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN regDate DATETIME;
+ALTER TABLE users ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN avatarURL TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN rating REAL NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_adverts_author ON adverts(authorLogin);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_adverts_status;
+
+ALTER TABLE adverts DROP COLUMN status;
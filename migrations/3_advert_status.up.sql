@@ -0,0 +1,3 @@
+ALTER TABLE adverts ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+
+CREATE INDEX IF NOT EXISTS idx_adverts_status ON adverts(status);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id INTEGER PRIMARY KEY,
+    userId INTEGER NOT NULL,
+    familyId TEXT NOT NULL,
+    tokenHash TEXT NOT NULL UNIQUE,
+    createdAt DATETIME NOT NULL,
+    expiresAt DATETIME NOT NULL,
+    usedAt DATETIME,
+    revokedAt DATETIME,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(familyId);
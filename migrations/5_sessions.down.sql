@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_session;
+ALTER TABLE refresh_tokens RENAME COLUMN sessionId TO familyId;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(familyId);
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY,
+    userId INTEGER NOT NULL,
+    userAgent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    createdAt DATETIME NOT NULL,
+    lastSeenAt DATETIME NOT NULL,
+    revokedAt DATETIME,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(userId);
+
+-- every existing refresh token family becomes a session
+INSERT INTO sessions (id, userId, createdAt, lastSeenAt, revokedAt)
+SELECT familyId, userId, MIN(createdAt), MAX(createdAt), MAX(revokedAt)
+FROM refresh_tokens GROUP BY familyId;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_family;
+ALTER TABLE refresh_tokens RENAME COLUMN familyId TO sessionId;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(sessionId);
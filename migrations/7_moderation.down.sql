@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS bans;
+
+ALTER TABLE adverts DROP COLUMN moderationReason;
+
+DROP TABLE IF EXISTS reports;
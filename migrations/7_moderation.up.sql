@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS reports (
+    id INTEGER PRIMARY KEY,
+    advertId INTEGER NOT NULL,
+    reporterId INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    createdAt DATETIME NOT NULL,
+    resolvedAt DATETIME,
+    UNIQUE (advertId, reporterId),
+    FOREIGN KEY (advertId) REFERENCES adverts(id) ON DELETE CASCADE,
+    FOREIGN KEY (reporterId) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_open ON reports(advertId) WHERE resolvedAt IS NULL;
+
+ALTER TABLE adverts ADD COLUMN moderationReason TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS bans (
+    id INTEGER PRIMARY KEY,
+    userId INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    createdAt DATETIME NOT NULL,
+    createdBy INTEGER,
+    expiresAt DATETIME,
+    liftedAt DATETIME,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_bans_user ON bans(userId);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_bans_active;
+
+ALTER TABLE bans DROP COLUMN appealedAt;
+ALTER TABLE bans DROP COLUMN appeal;
@@ -0,0 +1,4 @@
+ALTER TABLE bans ADD COLUMN appeal TEXT NOT NULL DEFAULT '';
+ALTER TABLE bans ADD COLUMN appealedAt DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_bans_active ON bans(userId) WHERE liftedAt IS NULL;
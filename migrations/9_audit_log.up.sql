@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY,
+    event TEXT NOT NULL,
+    actorId INTEGER,
+    actorLogin TEXT NOT NULL DEFAULT '',
+    target TEXT NOT NULL DEFAULT '',
+    details TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    requestId TEXT NOT NULL DEFAULT '',
+    createdAt DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(createdAt);
+CREATE INDEX IF NOT EXISTS idx_audit_log_event ON audit_log(event);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actorLogin);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target);
+
+-- audit log is append-only
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit log is append-only');
+END;